@@ -0,0 +1,134 @@
+package slabgo
+
+import (
+	"errors"
+	"math/bits"
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// Range of size classes of Allocator
+const (
+	MinClassSize = 8
+	MaxClassSize = 64 << 10
+)
+
+// Allocator allocates byte buffers from caches of size classes, like kmalloc.
+// Sizes of classes are powers of two from MinClassSize to MaxClassSize.
+type Allocator struct {
+	caches    []*Cache // by class
+	panicFree bool
+}
+
+// Statistics of a size class
+type ClassStats struct {
+	Size int // bytes of buffers in the class
+	CacheStats
+}
+
+// Create an Allocator with options applied to the cache of each class.
+// If `opts.Name` is set, a cache is named it followed by "-" and size of the class (e.g. "kmalloc-64").
+// If `opts.ObjLen` is zero, it is chosen from size of the class.
+func NewAllocator(opts CacheOptions) (*Allocator, error) {
+	a := &Allocator{panicFree: opts.PanicOnInvalidFree}
+	for size := MinClassSize; size <= MaxClassSize; size <<= 1 {
+		copts := opts
+		if opts.Name != "" {
+			copts.Name = opts.Name + "-" + strconv.Itoa(size)
+		}
+		if opts.ObjLen == 0 {
+			copts.ObjLen = classObjLen(size)
+		}
+		obj := reflect.New(reflect.ArrayOf(size, reflect.TypeOf(byte(0)))).Elem().Interface()
+		c, err := NewCacheE(obj, copts)
+		if err != nil {
+			a.Destroy()
+			return nil, err
+		}
+		a.caches = append(a.caches, c)
+	}
+	return a, nil
+}
+
+// return length of object array for a class, slabs of large classes have fewer objects
+func classObjLen(size int) int {
+	n := (256 << 10) / size
+	if n > 256 {
+		return 256
+	} else if n < 8 {
+		return 8
+	}
+	return n
+}
+
+// return index of the smallest class that fits `n` bytes
+func classOf(n int) int {
+	if n <= MinClassSize {
+		return 0
+	}
+	return bits.Len(uint(n-1)) - bits.Len(MinClassSize-1)
+}
+
+// Allocate a buffer of `n` bytes, its capacity is size of the class.
+// return nil if `n` is negative or larger than MaxClassSize, or there is no available object.
+//
+// NOTE: The buffer is not cleared unless `CacheOptions.ZeroOnAlloc` is set.
+func (a *Allocator) Alloc(n int) []byte {
+	if n < 0 || n > MaxClassSize {
+		return nil
+	}
+	i := classOf(n)
+	obj := a.caches[i].Alloc()
+	if obj == nil {
+		return nil
+	}
+	return unsafe.Slice((*byte)(reflect.ValueOf(obj).UnsafePointer()), MinClassSize<<i)[:n]
+}
+
+// Return a buffer to the cache of its class.
+// `b` must be a buffer returned by `Allocator.Alloc`, or a slice of it that has the same start and capacity.
+func (a *Allocator) Free(b []byte) bool {
+	return a.FreeErr(b) == nil
+}
+
+// Return a buffer to the cache of its class.
+// return ErrNotOwned if capacity of `b` is not size of any class, or an error same as `Cache.FreePtrErr`.
+func (a *Allocator) FreeErr(b []byte) error {
+	i := classOf(cap(b))
+	if cap(b) != MinClassSize<<i || i >= len(a.caches) {
+		if a.panicFree {
+			panic(ErrNotOwned)
+		}
+		return ErrNotOwned
+	}
+	return a.caches[i].FreePtrErr(unsafe.Pointer(unsafe.SliceData(b)))
+}
+
+// Return the cache of a class that fits `n` bytes, or nil if `n` is larger than MaxClassSize
+func (a *Allocator) Cache(n int) *Cache {
+	if n < 0 || n > MaxClassSize {
+		return nil
+	}
+	return a.caches[classOf(n)]
+}
+
+// Append statistics of each class to `s` in order of size, and return it
+func (a *Allocator) ReadStats(s []ClassStats) []ClassStats {
+	for i, c := range a.caches {
+		cs := ClassStats{Size: MinClassSize << i}
+		c.ReadStats(&cs.CacheStats)
+		s = append(s, cs)
+	}
+	return s
+}
+
+// Explicitly destroy caches of all classes.
+// return errors of `Cache.Destroy` joined.
+func (a *Allocator) Destroy() error {
+	var errs []error
+	for _, c := range a.caches {
+		errs = append(errs, c.Destroy())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+package slabgo_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/k-sone/slabgo"
+)
+
+func TestAllocatorClasses(t *testing.T) {
+	a, err := slabgo.NewAllocator(slabgo.CacheOptions{Name: "kmalloc"})
+	if err != nil {
+		t.Fatalf("NewAllocator() - unexpected error [%v]", err)
+	}
+
+	for _, c := range []struct{ n, size int }{
+		{0, 8}, {1, 8}, {8, 8}, {9, 16}, {100, 128}, {4096, 4096}, {4097, 8192}, {slabgo.MaxClassSize, slabgo.MaxClassSize},
+	} {
+		b := a.Alloc(c.n)
+		if len(b) != c.n || cap(b) != c.size {
+			t.Errorf("Alloc(%d) - len, cap: expected [%d, %d], actual [%d, %d]", c.n, c.n, c.size, len(b), cap(b))
+		}
+		for i := range b {
+			b[i] = byte(i)
+		}
+		if cache := a.Cache(c.n); cache.Name() != "kmalloc-"+strconv.Itoa(c.size) {
+			t.Errorf("Cache(%d) - unexpected cache [%s]", c.n, cache.Name())
+		}
+		if !a.Free(b[:0]) {
+			t.Errorf("Free() - failed to free %d bytes", c.n)
+		}
+		if err := a.FreeErr(b); !errors.Is(err, slabgo.ErrDoubleFree) {
+			t.Errorf("FreeErr() - expected [%v], actual [%v]", slabgo.ErrDoubleFree, err)
+		}
+	}
+
+	for _, n := range []int{-1, slabgo.MaxClassSize + 1} {
+		if b := a.Alloc(n); b != nil {
+			t.Errorf("Alloc(%d) - unexpected buffer", n)
+		}
+	}
+	for _, b := range [][]byte{nil, make([]byte, 24), a.Alloc(16)[1:]} {
+		if err := a.FreeErr(b); !errors.Is(err, slabgo.ErrNotOwned) {
+			t.Errorf("FreeErr() cap %d - expected [%v], actual [%v]", cap(b), slabgo.ErrNotOwned, err)
+		}
+	}
+
+	if err := a.Destroy(); !errors.Is(err, slabgo.ErrInUse) {
+		t.Errorf("Destroy() - expected [%v], actual [%v]", slabgo.ErrInUse, err)
+	}
+	for _, c := range slabgo.Caches() {
+		if strings.HasPrefix(c.Name(), "kmalloc-") {
+			t.Errorf("Destroy() - cache %s remains registered", c.Name())
+		}
+	}
+}
+
+func TestAllocatorStats(t *testing.T) {
+	a, _ := slabgo.NewAllocator(slabgo.CacheOptions{})
+	defer a.Destroy()
+
+	var bufs [][]byte
+	for _, n := range []int{5, 8, 30, 32, 32, 1000} {
+		bufs = append(bufs, a.Alloc(n))
+	}
+	a.Free(bufs[0])
+
+	stats := a.ReadStats(nil)
+	if len(stats) != 14 || stats[0].Size != 8 || stats[13].Size != 64<<10 {
+		t.Fatalf("ReadStats() - unexpected classes %+v", stats)
+	}
+	inuse := map[int]int{8: 1, 32: 3, 1024: 1}
+	for _, s := range stats {
+		if s.InuseObjs != inuse[s.Size] {
+			t.Errorf("ReadStats() - class %d: inuse objs expected [%d], actual [%d]", s.Size, inuse[s.Size], s.InuseObjs)
+		}
+		if s.TotalObjs > 0 && s.CacheSize != uint64(s.Size*s.TotalObjs) {
+			t.Errorf("ReadStats() - class %d: unexpected cache size [%d]", s.Size, s.CacheSize)
+		}
+	}
+	if objLen := a.Cache(64 << 10).ObjectLen(); objLen != 8 {
+		t.Errorf("ObjectLen() - expected [8], actual [%d]", objLen)
+	}
+}
+
+func TestAllocatorConcurrent(t *testing.T) {
+	a, _ := slabgo.NewAllocator(slabgo.CacheOptions{Concurrent: true})
+	defer a.Destroy()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				b := a.Alloc(i)
+				for j := range b {
+					b[j] = byte(w)
+				}
+				for j := range b {
+					if b[j] != byte(w) {
+						t.Errorf("Alloc() - buffer is shared")
+						return
+					}
+				}
+				if !a.Free(b) {
+					t.Errorf("Free() - failed to free %d bytes", i)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,175 @@
+package slabgo
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// Allocate objects from cache as many as length of `dst`.
+// return number of objects allocated, it is less than length of `dst`
+// if there is no available slab.
+func (c *Cache) AllocN(dst []interface{}) int {
+	return c.allocEach(len(dst), func(i int, obj interface{}) { dst[i] = obj })
+}
+
+// Return objects to cache.
+// return number of objects freed, invalid pointers are skipped.
+func (c *Cache) FreeN(ptrs []unsafe.Pointer) int {
+	return c.freeEach(len(ptrs), func(i int) uintptr { return uintptr(ptrs[i]) })
+}
+
+// allocate `n` objects, and pass each to `fn`
+func (c *Cache) allocEach(n int, fn func(i int, obj interface{})) (allocated int) {
+	if c.prepares {
+		each := fn
+		fn = func(i int, obj interface{}) {
+			c.prepare(obj)
+			each(i, obj)
+		}
+	}
+	if c.mags != nil {
+		// take objects from magazine at first
+		m := c.magazine()
+		for allocated < n && len(m.rounds) > 0 {
+			fn(allocated, m.pop())
+			allocated++
+		}
+		m.mu.Unlock()
+	}
+	if allocated == n {
+		return
+	}
+
+	c.lock()
+	k := c.allocN(n-allocated, func(i int, obj interface{}) { fn(allocated+i, obj) })
+	errs := c.takeCorruptions()
+	c.unlock()
+
+	c.report(errs)
+	allocated += k
+	if c.mags != nil {
+		// slabs run dry, take objects held by other magazines
+		for allocated < n {
+			obj := c.steal()
+			if obj == nil {
+				break
+			}
+			fn(allocated, obj)
+			allocated++
+		}
+	}
+	if allocated < n {
+		atomic.AddUint64(&c.failed, uint64(n-allocated))
+	}
+	return
+}
+
+func (c *Cache) allocN(n int, fn func(i int, obj interface{})) (allocated int) {
+	if c.destroyed {
+		return
+	}
+	if rest := c.maxObjs - c.inuseObjs; n > rest {
+		n = rest
+	}
+	if c.debug || c.track {
+		// objects must be checked or recorded one by one
+		for allocated < n {
+			obj := c.alloc()
+			if obj == nil {
+				break
+			}
+			fn(allocated, obj)
+			allocated++
+		}
+		return
+	}
+
+	for allocated < n {
+		s := c.available()
+		if s == nil {
+			break
+		}
+
+		k := s.allocN(n-allocated, func(i int, obj interface{}) { fn(allocated+i, obj) })
+		c.touch(s)
+		c.inuseObjs += k
+		c.allocs += uint64(k)
+		allocated += k
+	}
+	if c.inuseObjs > c.peakObjs {
+		c.peakObjs = c.inuseObjs
+	}
+	return
+}
+
+// free objects that `ptrAt` returns
+func (c *Cache) freeEach(n int, ptrAt func(i int) uintptr) (freed int) {
+	var failed uintptr
+	var ferr error
+	fail := func(ptr uintptr, err error) {
+		if ferr == nil {
+			failed, ferr = ptr, err
+		}
+	}
+
+	if c.mags != nil {
+		for i := 0; i < n; i++ {
+			ptr := ptrAt(i)
+			if err := c.magFree(ptr); err != nil {
+				fail(ptr, err)
+			} else {
+				freed++
+			}
+		}
+	} else {
+		c.lock()
+		for i := 0; i < n; i++ {
+			ptr := ptrAt(i)
+			if err := c.free(ptr); err != nil {
+				fail(ptr, err)
+			} else {
+				freed++
+			}
+		}
+		errs := c.takeCorruptions()
+		c.unlock()
+
+		c.report(errs)
+	}
+
+	c.freeFailed(failed, ferr)
+	if freed > 0 && atomic.LoadInt32(&c.waiters) > 0 {
+		c.wakeup()
+	}
+	return
+}
+
+// allocate at most `n` objects by scanning bufctl in bytes
+func (s *slab) allocN(n int, fn func(i int, obj interface{})) (allocated int) {
+	for b := s.first >> 3; b < len(s.bufctl) && allocated < n; b++ {
+		for s.bufctl[b] != 0xff && allocated < n {
+			j := ntzMatrix[s.bufctl[b]]
+			i := b<<3 + int(j)
+			s.touched[b] |= 1 << j
+			s.own(i)
+			fn(allocated, s.chunk[i])
+			s.bufctl[b] |= 1 << j
+			allocated++
+		}
+	}
+	s.inuse += allocated
+
+	// find a next object that are unused
+	if s.total > s.inuse {
+		for b := s.first >> 3; b < len(s.bufctl); b++ {
+			if s.bufctl[b] != 0xff {
+				s.first = b<<3 + int(ntzMatrix[s.bufctl[b]])
+				return
+			}
+		}
+	}
+
+	// set out of range
+	s.first = len(s.bufctl) << 3
+	return
+}
@@ -0,0 +1,114 @@
+package slabgo_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+func checkUnique(t *testing.T, name string, objs []interface{}) {
+	seen := make(map[interface{}]bool)
+	for i, o := range objs {
+		if _, ok := o.(*Foo); !ok {
+			t.Errorf("%s - invalid object at %d", name, i)
+		} else if seen[o] {
+			t.Errorf("%s - duplicated object at %d", name, i)
+		}
+		seen[o] = true
+	}
+}
+
+func TestBatchAllocN(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	objLen := 16
+	for _, opts := range []slabgo.CacheOptions{
+		{ObjLen: objLen},
+		{ObjLen: objLen, Concurrent: true},
+		{ObjLen: objLen, Debug: true},
+	} {
+		opts.Grower = func(s *slabgo.CacheStats) int {
+			if s.TotalSlabs < 2 {
+				return 1
+			}
+			return 0
+		}
+		cache := slabgo.NewCache(foo, opts)
+
+		objs := make([]interface{}, 20)
+		if n := cache.AllocN(objs); n != len(objs) {
+			t.Fatalf("AllocN() - expected [%d], actual [%d]", len(objs), n)
+		}
+		checkUnique(t, "AllocN() 1st", objs)
+
+		ptrs := []unsafe.Pointer{
+			unsafe.Pointer(objs[1].(*Foo)),
+			unsafe.Pointer(objs[3].(*Foo)),
+			unsafe.Pointer(objs[5].(*Foo)),
+			unsafe.Pointer(objs[17].(*Foo)),
+			unsafe.Pointer(&foo),
+		}
+		if n := cache.FreeN(ptrs); n != 4 {
+			t.Errorf("FreeN() - expected [4], actual [%d]", n)
+		}
+
+		more := make([]interface{}, 20)
+		if n := cache.AllocN(more); n != objLen*2-16 {
+			t.Errorf("AllocN() 2nd - expected [%d], actual [%d]", objLen*2-16, n)
+		}
+		live := append(objs[:0:0], objs[0], objs[2], objs[4])
+		live = append(live, objs[6:17]...)
+		live = append(live, objs[18:]...)
+		live = append(live, more[:objLen*2-16]...)
+		checkUnique(t, "AllocN() 2nd", live)
+
+		name := "AllocN()"
+		stats.TotalSlabs = 2
+		stats.InuseSlabs = 2
+		stats.TotalObjs = objLen * 2
+		stats.InuseObjs = objLen * 2
+		stats.Allocs = uint64(20 + objLen*2 - 16)
+		stats.Frees = 4
+		checkStats(t, name, cache, &stats)
+	}
+}
+
+func TestBatchTyped(t *testing.T) {
+	var stats slabgo.CacheStats
+
+	objLen := 8
+	cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{
+		CacheOptions: slabgo.CacheOptions{ObjLen: objLen},
+	})
+
+	foos := make([]*Foo, objLen*3+1)
+	if n := cache.AllocN(foos); n != len(foos) {
+		t.Fatalf("AllocN() - expected [%d], actual [%d]", len(foos), n)
+	}
+	for i, f := range foos {
+		if f == nil {
+			t.Fatalf("AllocN() - nil at %d", i)
+		}
+		f.count = int64(i)
+	}
+	for i, f := range foos {
+		if f.count != int64(i) {
+			t.Errorf("AllocN() - object shared at %d", i)
+		}
+	}
+
+	if n := cache.FreeN(foos[1:]); n != len(foos)-1 {
+		t.Errorf("FreeN() - expected [%d], actual [%d]", len(foos)-1, n)
+	}
+
+	name := "typed FreeN()"
+	stats.TotalSlabs = 4
+	stats.InuseSlabs = 1
+	stats.TotalObjs = objLen * 4
+	stats.InuseObjs = 1
+	stats.Allocs = uint64(len(foos))
+	stats.Frees = uint64(len(foos) - 1)
+	checkStats(t, name, cache.Cache(), &stats)
+}
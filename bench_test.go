@@ -1,6 +1,7 @@
 package slabgo_test
 
 import (
+	"math/rand"
 	"testing"
 	"unsafe"
 
@@ -111,3 +112,215 @@ func BenchmarkSlabPtr100000(b *testing.B) {
 		slabAllocatePtr(100000, c)
 	}
 }
+
+func typedAllocate(n int, c *slabgo.TypedCache[Bar]) {
+	z := make([]*Bar, n)
+	for i := 0; i < n; i++ {
+		z[i] = c.Alloc()
+	}
+	for i := 0; i < n; i++ {
+		c.Free(z[i])
+		z[i] = nil
+	}
+}
+
+func BenchmarkTyped1000(b *testing.B) {
+	c := slabgo.NewTypedCacheSimple[Bar]()
+	for i := 0; i < b.N; i++ {
+		typedAllocate(1000, c)
+	}
+}
+
+func BenchmarkTyped10000(b *testing.B) {
+	c := slabgo.NewTypedCacheSimple[Bar]()
+	for i := 0; i < b.N; i++ {
+		typedAllocate(10000, c)
+	}
+}
+
+func BenchmarkTyped100000(b *testing.B) {
+	c := slabgo.NewTypedCacheSimple[Bar]()
+	for i := 0; i < b.N; i++ {
+		typedAllocate(100000, c)
+	}
+}
+
+func slabAllocateParallel(b *testing.B, n int, opts slabgo.CacheOptions) {
+	var a Bar
+	c := slabgo.NewCache(a, opts)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			slabAllocatePtr(n, c)
+		}
+	})
+}
+
+func BenchmarkBuiltinParallel1000(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			builtinAllocate(1000)
+		}
+	})
+}
+
+func BenchmarkSlabMutexParallel1000(b *testing.B) {
+	slabAllocateParallel(b, 1000, slabgo.CacheOptions{Concurrent: true, MagazineSize: -1})
+}
+
+func BenchmarkSlabConcurrentParallel1000(b *testing.B) {
+	slabAllocateParallel(b, 1000, slabgo.CacheOptions{Concurrent: true})
+}
+
+func BenchmarkSlabConcurrentParallel10000(b *testing.B) {
+	slabAllocateParallel(b, 10000, slabgo.CacheOptions{Concurrent: true})
+}
+
+func slabAllocateBatch(z []interface{}, ptrs []unsafe.Pointer, c *slabgo.Cache) {
+	c.AllocN(z)
+	for i := range z {
+		ptrs[i] = unsafe.Pointer(z[i].(*Bar))
+		z[i] = nil
+	}
+	c.FreeN(ptrs)
+}
+
+func benchmarkSlabBatch(b *testing.B, n int) {
+	var a Bar
+	c := slabgo.NewCacheSimple(a)
+	z := make([]interface{}, n)
+	ptrs := make([]unsafe.Pointer, n)
+	for i := 0; i < b.N; i++ {
+		slabAllocateBatch(z, ptrs, c)
+	}
+}
+
+func benchmarkTypedBatch(b *testing.B, n int) {
+	c := slabgo.NewTypedCacheSimple[Bar]()
+	z := make([]*Bar, n)
+	for i := 0; i < b.N; i++ {
+		c.AllocN(z)
+		c.FreeN(z)
+	}
+}
+
+func BenchmarkSlabBatch1000(b *testing.B) {
+	benchmarkSlabBatch(b, 1000)
+}
+
+func BenchmarkTypedBatch1000(b *testing.B) {
+	benchmarkTypedBatch(b, 1000)
+}
+
+func BenchmarkSlabBatch10000(b *testing.B) {
+	benchmarkSlabBatch(b, 10000)
+}
+
+func BenchmarkTypedBatch10000(b *testing.B) {
+	benchmarkTypedBatch(b, 10000)
+}
+
+// scatter 2500 objects over slabs for 10000 objects, and replace an object at random.
+// report slabs in use at the end.
+func benchmarkPolicyChurn(b *testing.B, p slabgo.Policy) {
+	var a Bar
+	var stats slabgo.CacheStats
+	c := slabgo.NewCache(a, slabgo.CacheOptions{ObjLen: 64, Policy: p})
+	r := rand.New(rand.NewSource(1))
+
+	live := make([]*Bar, 10000)
+	for i := range live {
+		live[i] = c.Alloc().(*Bar)
+	}
+	r.Shuffle(len(live), func(i, j int) { live[i], live[j] = live[j], live[i] })
+	for _, o := range live[2500:] {
+		c.FreePtr(unsafe.Pointer(o))
+	}
+	live = live[:2500]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := r.Intn(len(live))
+		c.FreePtr(unsafe.Pointer(live[j]))
+		live[j] = c.Alloc().(*Bar)
+	}
+	b.StopTimer()
+
+	c.ReadStats(&stats)
+	b.ReportMetric(float64(stats.InuseSlabs), "inuse-slabs")
+}
+
+func BenchmarkPolicyChurnAddress(b *testing.B) {
+	benchmarkPolicyChurn(b, slabgo.PolicyAddress)
+}
+
+func BenchmarkPolicyChurnMostFull(b *testing.B) {
+	benchmarkPolicyChurn(b, slabgo.PolicyMostFull)
+}
+
+func BenchmarkPolicyChurnLRU(b *testing.B) {
+	benchmarkPolicyChurn(b, slabgo.PolicyLRU)
+}
+
+// free a half of objects over `n` slabs, so that all slabs are partial.
+// then free and allocate an object at random.
+func benchmarkManySlabs(b *testing.B, n int, opts slabgo.CacheOptions) {
+	var a Bar
+	opts.ObjLen = 8
+	c := slabgo.NewCache(a, opts)
+	r := rand.New(rand.NewSource(1))
+
+	live := make([]*Bar, n*opts.ObjLen)
+	for i := range live {
+		live[i] = c.Alloc().(*Bar)
+	}
+	for i := 0; i < len(live)/2; i++ {
+		c.FreePtr(unsafe.Pointer(live[i*2]))
+		live[i] = live[i*2+1]
+	}
+	live = live[:len(live)/2]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := r.Intn(len(live))
+		c.FreePtr(unsafe.Pointer(live[j]))
+		live[j] = c.Alloc().(*Bar)
+	}
+}
+
+func BenchmarkSlabMany10kSlabs(b *testing.B) {
+	benchmarkManySlabs(b, 10000, slabgo.CacheOptions{})
+}
+
+func BenchmarkSlabMany100kSlabs(b *testing.B) {
+	benchmarkManySlabs(b, 100000, slabgo.CacheOptions{})
+}
+
+func BenchmarkSlabConcurrentMany100kSlabs(b *testing.B) {
+	benchmarkManySlabs(b, 100000, slabgo.CacheOptions{Concurrent: true})
+}
+
+func BenchmarkSlabConcurrentMany100kSlabsReap(b *testing.B) {
+	// an empty slab is destroyed and created one by one
+	benchmarkManySlabs(b, 100000, slabgo.CacheOptions{
+		Concurrent: true,
+		Reaper:     func(s *slabgo.CacheStats) int { return 1 },
+		Grower:     func(s *slabgo.CacheStats) int { return 1 },
+	})
+}
+
+var sink []byte
+
+func BenchmarkBuiltinBytes(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		sink = make([]byte, 8+i%4000)
+	}
+}
+
+func BenchmarkAllocatorBytes(b *testing.B) {
+	a, _ := slabgo.NewAllocator(slabgo.CacheOptions{})
+	defer a.Destroy()
+	for i := 0; i < b.N; i++ {
+		sink = a.Alloc(8 + i%4000)
+		a.Free(sink)
+	}
+}
@@ -0,0 +1,243 @@
+package slabgo
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// object held by a magazine
+type round struct {
+	obj  interface{}
+	ptr  uintptr
+	slab *slab
+}
+
+// Per-shard cache of free objects in front of the shared slab lists.
+// It refers to the magazine layer of Bonwick's slab allocator.
+type magazine struct {
+	mu     sync.Mutex
+	rounds []round
+	allocs uint64 // number of allocs through this magazine
+	frees  uint64 // number of frees through this magazine
+	_      [64]byte
+}
+
+func (m *magazine) clear() {
+	for i := range m.rounds {
+		m.rounds[i] = round{}
+	}
+	m.rounds = m.rounds[:0]
+	m.allocs = 0
+	m.frees = 0
+}
+
+// take the last object, it becomes owned by user
+func (m *magazine) pop() interface{} {
+	last := len(m.rounds) - 1
+	r := m.rounds[last]
+	m.rounds[last] = round{}
+	m.rounds = m.rounds[:last]
+	m.allocs++
+	i, _ := r.slab.indexOf(r.ptr)
+	r.slab.own(i)
+	return r.obj
+}
+
+func newMagazines(size int) []magazine {
+	mags := make([]magazine, runtime.GOMAXPROCS(0))
+	for i := range mags {
+		mags[i].rounds = make([]round, 0, size)
+	}
+	return mags
+}
+
+// lock and return a magazine.
+// The first one that is not locked from a random start is taken, so that goroutines running
+// in parallel use different ones without contending on the same magazine.
+// If all are locked, it waits for one chosen in turn.
+func (c *Cache) magazine() *magazine {
+	n := uint32(len(c.mags))
+	var start uint32
+	if n > 1 {
+		start = rand.Uint32N(n)
+	}
+	for i := uint32(0); i < n; i++ {
+		if m := &c.mags[(start+i)%n]; m.mu.TryLock() {
+			return m
+		}
+	}
+	m := &c.mags[c.turn.Add(1)%n]
+	m.mu.Lock()
+	return m
+}
+
+func (c *Cache) lockMags() {
+	for i := range c.mags {
+		c.mags[i].mu.Lock()
+	}
+}
+
+func (c *Cache) unlockMags() {
+	for i := len(c.mags) - 1; i > -1; i-- {
+		c.mags[i].mu.Unlock()
+	}
+}
+
+func (c *Cache) magAlloc() interface{} {
+	m := c.magazine()
+	if len(m.rounds) == 0 {
+		c.refill(m)
+	}
+	if len(m.rounds) > 0 {
+		obj := m.pop()
+		m.mu.Unlock()
+		return obj
+	}
+	m.mu.Unlock()
+
+	// there is no available slab, though other magazines may hold objects within the limit
+	return c.steal()
+}
+
+// load a half of magazine from slabs, the magazine must be locked
+func (c *Cache) refill(m *magazine) {
+	c.lock()
+	defer c.unlock()
+
+	n := c.magSize/2 + 1
+	if c.maxObjs < math.MaxInt {
+		// share the rest of limit with other magazines
+		if rest := (c.maxObjs - c.inuseObjs) / len(c.mags); rest < n {
+			n = max(rest, 1)
+		}
+	}
+	for i := 0; i < n; i++ {
+		o := c.alloc()
+		if o == nil {
+			break
+		}
+		ptr := addrOf(o)
+		m.rounds = append(m.rounds, round{obj: o, ptr: ptr, slab: c.addrs.get(ptr)})
+	}
+	// objects are counted when they are taken from magazine
+	c.allocs -= uint64(len(m.rounds))
+}
+
+// take an object from magazines of other shards
+func (c *Cache) steal() (obj interface{}) {
+	for i := range c.mags {
+		m := &c.mags[i]
+		m.mu.Lock()
+		if len(m.rounds) > 0 {
+			obj = m.pop()
+		}
+		m.mu.Unlock()
+		if obj != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *Cache) magFree(ptr uintptr) error {
+	s, i, err := c.lookup(ptr)
+	if err != nil {
+		return err
+	}
+	if !s.disown(i) {
+		// unused, or held by a magazine
+		return ErrDoubleFree
+	}
+	obj := s.chunk[i]
+	if c.freeHooks {
+		c.freed(obj)
+	}
+
+	m := c.magazine()
+	defer m.mu.Unlock()
+
+	if len(m.rounds) == cap(m.rounds) {
+		// return a half of magazine to slabs
+		c.lock()
+		c.flush(m, c.magSize/2)
+		c.unlock()
+	}
+
+	m.rounds = append(m.rounds, round{obj: obj, ptr: ptr, slab: s})
+	m.frees++
+	return nil
+}
+
+// return all objects held by magazines to slabs, both locks must be held
+func (c *Cache) drain() {
+	for i := range c.mags {
+		c.flush(&c.mags[i], 0)
+	}
+}
+
+// return objects held by a magazine to slabs until `n` remain, both locks must be held
+func (c *Cache) flush(m *magazine, n int) {
+	for len(m.rounds) > n {
+		last := len(m.rounds) - 1
+		if c.release(m.rounds[last].ptr, false) == nil {
+			// counted when they are put into magazine
+			c.frees--
+		} else {
+			// not freed, though counted by magazine
+			m.frees--
+		}
+		m.rounds[last] = round{}
+		m.rounds = m.rounds[:last]
+	}
+}
+
+// return a slab and index of object that `ptr` points without lock
+func (c *Cache) lookup(ptr uintptr) (*slab, int, error) {
+	if s := c.addrs.load(ptr); s != nil {
+		j, err := s.indexOf(ptr)
+		if err != nil {
+			return nil, -1, err
+		}
+		return s, j, nil
+	}
+	return nil, -1, ErrNotOwned
+}
+
+// mark an object as owned by user on magazine mode,
+// because objects held by magazines are also in use within slabs
+func (s *slab) own(i int) {
+	if s.owned == nil {
+		return
+	}
+	w, bit := &s.owned[i>>5], uint32(1)<<uint(i&0x1f)
+	for {
+		if old := w.Load(); w.CompareAndSwap(old, old|bit) {
+			return
+		}
+	}
+}
+
+// unmark an object, return false if it is not owned by user
+func (s *slab) disown(i int) bool {
+	w, bit := &s.owned[i>>5], uint32(1)<<uint(i&0x1f)
+	for {
+		old := w.Load()
+		if old&bit == 0 {
+			return false
+		} else if w.CompareAndSwap(old, old&^bit) {
+			return true
+		}
+	}
+}
+
+// whether an object is owned by user
+func (s *slab) isOwned(i int) bool {
+	return s.owned[i>>5].Load()&(1<<uint(i&0x1f)) != 0
+}
+
+func addrOf(obj interface{}) uintptr {
+	return reflect.ValueOf(obj).Pointer()
+}
@@ -0,0 +1,150 @@
+package slabgo_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+func concurrentAllocFree(t *testing.T, cache *slabgo.Cache, workers, loops, batch int) {
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			foos := make([]*Foo, batch)
+			for l := 0; l < loops; l++ {
+				for i := range foos {
+					f, ok := cache.Alloc().(*Foo)
+					if !ok {
+						t.Errorf("Alloc() - failed at worker %d", id)
+						return
+					}
+					f.count = id
+					foos[i] = f
+				}
+				for i, f := range foos {
+					if f.count != id {
+						t.Errorf("Alloc() - object shared with worker %d", f.count)
+					}
+					if !cache.FreePtr(unsafe.Pointer(f)) {
+						t.Errorf("FreePtr() - failed at worker %d", id)
+					}
+					foos[i] = nil
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+}
+
+func TestConcurrentAllocFree(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	workers, loops, batch := 8, 100, 100
+	for _, size := range []int{0, 1, 8, -1} {
+		cache := slabgo.NewCache(foo, slabgo.CacheOptions{
+			ObjLen:       32,
+			Concurrent:   true,
+			MagazineSize: size,
+		})
+		concurrentAllocFree(t, cache, workers, loops, batch)
+
+		cache.ReadStats(&stats)
+		if stats.InuseObjs != 0 {
+			t.Errorf("magazine %d - inuse objs: expected [0], actual [%d]", size, stats.InuseObjs)
+		}
+		if n := uint64(workers * loops * batch); stats.Allocs != n || stats.Frees != n {
+			t.Errorf("magazine %d - allocs/frees: expected [%d], actual [%d/%d]", size, n, stats.Allocs, stats.Frees)
+		}
+	}
+}
+
+func TestConcurrentReap(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{
+		ObjLen:     8,
+		Reaper:     func(s *slabgo.CacheStats) int { return 1 },
+		Concurrent: true,
+	})
+	concurrentAllocFree(t, cache, 4, 50, 64)
+
+	cache.ReadStats(&stats)
+	if stats.InuseObjs != 0 {
+		t.Errorf("inuse objs: expected [0], actual [%d]", stats.InuseObjs)
+	}
+}
+
+func TestConcurrentFree(t *testing.T) {
+	var foo Foo
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{Concurrent: true})
+
+	f := cache.Alloc().(*Foo)
+	if cache.Free(nil) {
+		t.Error("Free() - nil")
+	}
+	if cache.FreePtr(unsafe.Pointer(&foo)) {
+		t.Error("FreePtr() - not allocated")
+	}
+	if err := cache.FreePtrErr(unsafe.Add(unsafe.Pointer(f), 1)); !errors.Is(err, slabgo.ErrMisaligned) {
+		t.Errorf("FreePtrErr() - misaligned: %v", err)
+	}
+	if !cache.Free(f) {
+		t.Error("Free() - failed")
+	}
+
+	cache.Destroy()
+	checkStats(t, "Destroy()", cache, &slabgo.CacheStats{})
+	if cache.Alloc() != nil {
+		t.Error("Alloc() - succeeded after Destroy()")
+	}
+}
+
+func TestConcurrentDoubleFree(t *testing.T) {
+	var foo Foo
+	for _, size := range []int{-1, 0, 2} {
+		cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, Concurrent: true, MagazineSize: size})
+
+		var foos []*Foo
+		for i := 0; i < 6; i++ {
+			foos = append(foos, cache.Alloc().(*Foo))
+		}
+		if err := cache.FreePtrErr(unsafe.Pointer(foos[0])); err != nil {
+			t.Errorf("magazine %d - FreePtrErr() failed: %v", size, err)
+		}
+		if err := cache.FreePtrErr(unsafe.Pointer(foos[0])); !errors.Is(err, slabgo.ErrDoubleFree) {
+			t.Errorf("magazine %d - FreePtrErr() double free: %v", size, err)
+		}
+		// the object is returned from a small magazine to slab
+		for _, f := range foos[1:] {
+			cache.FreePtr(unsafe.Pointer(f))
+		}
+		if err := cache.FreePtrErr(unsafe.Pointer(foos[0])); !errors.Is(err, slabgo.ErrDoubleFree) {
+			t.Errorf("magazine %d - FreePtrErr() double free after flush: %v", size, err)
+		}
+		var stats slabgo.CacheStats
+		if cache.ReadStats(&stats); stats.InuseObjs != 0 || stats.Allocs != 6 || stats.Frees != 6 {
+			t.Errorf("magazine %d - inuse, allocs, frees: expected [0, 6, 6], actual [%d, %d, %d]",
+				size, stats.InuseObjs, stats.Allocs, stats.Frees)
+		}
+		if a, b := cache.Alloc(), cache.Alloc(); a == b {
+			t.Errorf("magazine %d - Alloc() returned the same object twice", size)
+		}
+
+		// an object freed by pointer is not freed by handle
+		h, obj := cache.AllocHandle()
+		cache.Free(obj)
+		if err := cache.FreeHandle(h); err == nil {
+			t.Errorf("magazine %d - FreeHandle() double free", size)
+		}
+		if cache.Deref(h) != nil {
+			t.Errorf("magazine %d - Deref() freed object", size)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package slabgo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Errors wrapped by CorruptionError
+var (
+	ErrUseAfterFree = errors.New("slabgo: object is modified after free")
+	ErrRedzone      = errors.New("slabgo: red zone is overwritten")
+)
+
+const (
+	redzoneSize   = 8    // minimum bytes of red zone after each object
+	poisonFree    = 0x6b // pattern of freed object
+	poisonRedzone = 0xbb // pattern of red zone
+)
+
+type poisonMode int
+
+const (
+	poisonNone    poisonMode = iota // freed object is not poisoned
+	poisonPattern                   // freed object is filled with `poisonFree`
+	poisonZero                      // freed object is cleared, for object that has pointers
+)
+
+// CorruptionError reports an object that is modified unexpectedly on debug mode
+type CorruptionError struct {
+	Err    error   // ErrUseAfterFree or ErrRedzone
+	Slab   uintptr // starting address of object array within slab
+	Index  int     // index of object within slab
+	Offset int     // offset of first modified byte from the start of object
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("%s: slab %#x, index %d, offset %d", e.Err, e.Slab, e.Index, e.Offset)
+}
+
+func (e *CorruptionError) Unwrap() error {
+	return e.Err
+}
+
+// return element type of object array that has a red zone after object
+func redzoneType(otype reflect.Type) reflect.Type {
+	return reflect.StructOf([]reflect.StructField{
+		{Name: "Obj", Type: otype},
+		{Name: "Redzone", Type: reflect.ArrayOf(redzoneSize, reflect.TypeOf(byte(0)))},
+	})
+}
+
+// whether values of type `t` contain pointers
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array:
+		return t.Len() > 0 && hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map,
+		reflect.Pointer, reflect.Slice, reflect.String, reflect.UnsafePointer:
+		return true
+	default:
+		return false
+	}
+}
+
+func poisonModeOf(otype reflect.Type, ctor Constructor) poisonMode {
+	if ctor != nil {
+		return poisonNone
+	} else if hasPointers(otype) {
+		// garbage in pointers confuses GC
+		return poisonZero
+	}
+	return poisonPattern
+}
+
+// fill red zones, and poison objects of new slab
+func (c *Cache) initDebug(s *slab) {
+	for i := 0; i < s.total; i++ {
+		fill(c.redzone(s, i), poisonRedzone)
+		if c.poison == poisonPattern {
+			fill(c.object(s, i), poisonFree)
+		}
+	}
+}
+
+// verify an object that is allocated
+func (c *Cache) checkAlloc(s *slab, i int) {
+	switch c.poison {
+	case poisonPattern:
+		if !c.verify(s, i, c.object(s, i), poisonFree, 0, ErrUseAfterFree) {
+			fill(c.object(s, i), poisonFree)
+		}
+	case poisonZero:
+		if !c.verify(s, i, c.object(s, i), 0, 0, ErrUseAfterFree) {
+			reflect.ValueOf(s.chunk[i]).Elem().SetZero()
+		}
+	}
+	c.checkRedzone(s, i)
+}
+
+// verify and poison an object that is freed
+func (c *Cache) checkFree(s *slab, i int) {
+	c.checkRedzone(s, i)
+	switch c.poison {
+	case poisonPattern:
+		fill(c.object(s, i), poisonFree)
+	case poisonZero:
+		reflect.ValueOf(s.chunk[i]).Elem().SetZero()
+	}
+}
+
+func (c *Cache) checkRedzone(s *slab, i int) {
+	if !c.verify(s, i, c.redzone(s, i), poisonRedzone, int(c.objType.Size()), ErrRedzone) {
+		fill(c.redzone(s, i), poisonRedzone)
+	}
+}
+
+// report corruption if `mem` is not filled with `pattern`
+func (c *Cache) verify(s *slab, i int, mem []byte, pattern byte, offset int, err error) bool {
+	for j, b := range mem {
+		if b != pattern {
+			c.corruptions = append(c.corruptions, &CorruptionError{Err: err, Slab: s.smem, Index: i, Offset: offset + j})
+			return false
+		}
+	}
+	return true
+}
+
+// return corruptions detected, they must be reported without lock
+func (c *Cache) takeCorruptions() (errs []error) {
+	if len(c.corruptions) > 0 {
+		errs, c.corruptions = c.corruptions, nil
+	}
+	return
+}
+
+func (c *Cache) report(errs []error) {
+	for _, err := range errs {
+		if c.debugReport == nil {
+			panic(err)
+		}
+		c.debugReport(err)
+	}
+}
+
+// return raw memory of i-th object
+func (c *Cache) object(s *slab, i int) []byte {
+	start := uintptr(i) * s.objsize
+	return s.mem[start : start+c.objType.Size()]
+}
+
+// return raw memory of red zone after i-th object
+func (c *Cache) redzone(s *slab, i int) []byte {
+	start := uintptr(i) * s.objsize
+	return s.mem[start+c.objType.Size() : start+s.objsize]
+}
+
+func fill(mem []byte, pattern byte) {
+	for i := range mem {
+		mem[i] = pattern
+	}
+}
@@ -0,0 +1,194 @@
+package slabgo_test
+
+import (
+	"errors"
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+type Sample struct {
+	id    int64
+	value float64
+}
+
+func debugCache(obj interface{}, opts slabgo.CacheOptions, errs *[]error) *slabgo.Cache {
+	opts.Debug = true
+	opts.DebugReport = func(err error) { *errs = append(*errs, err) }
+	return slabgo.NewCache(obj, opts)
+}
+
+func checkCorruption(t *testing.T, name string, errs []error, exp error, index, offset int) {
+	if len(errs) != 1 {
+		t.Errorf("%s - errors: expected [1], actual %v", name, errs)
+		return
+	}
+	var ce *slabgo.CorruptionError
+	if !errors.As(errs[0], &ce) || !errors.Is(ce, exp) {
+		t.Errorf("%s - error: expected [%v], actual [%v]", name, exp, errs[0])
+		return
+	}
+	if ce.Index != index || ce.Offset != offset || ce.Slab == 0 {
+		t.Errorf("%s - position: expected [%d:%d], actual [%#x:%d:%d]", name, index, offset, ce.Slab, ce.Index, ce.Offset)
+	}
+}
+
+func TestDebugNoError(t *testing.T) {
+	var errs []error
+	var foo Foo
+	var sample Sample
+	var stats slabgo.CacheStats
+
+	for _, obj := range []interface{}{foo, sample} {
+		objLen := 8
+		cache := debugCache(obj, slabgo.CacheOptions{ObjLen: objLen}, &errs)
+
+		var objs []interface{}
+		for i := 0; i < objLen*2; i++ {
+			objs = append(objs, cache.Alloc())
+		}
+		for _, o := range objs[:objLen+1] {
+			if !cache.Free(o) {
+				t.Error("Free() - failed")
+			}
+		}
+		for i := 0; i < objLen; i++ {
+			cache.Alloc()
+		}
+
+		name := "debug"
+		stats.TotalSlabs = 2
+		stats.InuseSlabs = 2
+		stats.TotalObjs = objLen * 2
+		stats.InuseObjs = objLen*2 - 1
+		stats.Allocs = uint64(objLen * 3)
+		stats.Frees = uint64(objLen + 1)
+		checkStats(t, name, cache, &stats)
+	}
+	if len(errs) != 0 {
+		t.Errorf("debug - unexpected errors %v", errs)
+	}
+}
+
+func TestDebugPoison(t *testing.T) {
+	var errs []error
+	var sample Sample
+	cache := debugCache(sample, slabgo.CacheOptions{}, &errs)
+
+	s := cache.Alloc().(*Sample)
+	s.id = 1
+	s.value = 1.0
+	cache.Free(s)
+	if s.id != 0x6b6b6b6b6b6b6b6b {
+		t.Errorf("Free() - not poisoned %#x", s.id)
+	}
+
+	var foo Foo
+	cache = debugCache(foo, slabgo.CacheOptions{}, &errs)
+
+	f := cache.Alloc().(*Foo)
+	f.name = "foo"
+	f.next = f
+	cache.Free(f)
+	if f.name != "" || f.next != nil {
+		t.Errorf("Free() - not cleared %v", *f)
+	}
+	if len(errs) != 0 {
+		t.Errorf("Free() - unexpected errors %v", errs)
+	}
+}
+
+func TestDebugUseAfterFree(t *testing.T) {
+	var errs []error
+	var sample Sample
+	cache := debugCache(sample, slabgo.CacheOptions{}, &errs)
+
+	cache.Alloc()
+	s := cache.Alloc().(*Sample)
+	cache.Free(s)
+	s.value = 2.0
+	if cache.Alloc().(*Sample) != s {
+		t.Fatal("Alloc() - not reused")
+	}
+	checkCorruption(t, "pattern", errs, slabgo.ErrUseAfterFree, 1, int(unsafe.Offsetof(s.value)))
+
+	errs = nil
+	var foo Foo
+	cache = debugCache(foo, slabgo.CacheOptions{}, &errs)
+
+	f := cache.Alloc().(*Foo)
+	cache.Free(f)
+	f.count = 1
+	if cache.Alloc().(*Foo) != f || f.count != 0 {
+		t.Fatal("Alloc() - not reused")
+	}
+	checkCorruption(t, "zero", errs, slabgo.ErrUseAfterFree, 0, int(unsafe.Offsetof(f.count)))
+}
+
+func TestDebugRedzone(t *testing.T) {
+	var errs []error
+	var sample Sample
+	cache := debugCache(sample, slabgo.CacheOptions{}, &errs)
+
+	s := cache.Alloc().(*Sample)
+	overflow := (*[2]Sample)(unsafe.Pointer(s))
+	overflow[1].id = 1
+	cache.Free(s)
+	checkCorruption(t, "redzone", errs, slabgo.ErrRedzone, 0, int(unsafe.Sizeof(sample)))
+
+	// red zone is repaired
+	errs = nil
+	cache.Alloc()
+	if len(errs) != 0 {
+		t.Errorf("Alloc() - unexpected errors %v", errs)
+	}
+}
+
+func TestDebugConstructor(t *testing.T) {
+	var errs []error
+	var sample Sample
+	cache := debugCache(sample, slabgo.CacheOptions{
+		Constructor: func(objp interface{}) { objp.(*Sample).id = 1 },
+	}, &errs)
+
+	s := cache.Alloc().(*Sample)
+	cache.Free(s)
+	if s.id != 1 {
+		t.Errorf("Free() - poisoned constructed object %#x", s.id)
+	}
+	if len(errs) != 0 {
+		t.Errorf("Free() - unexpected errors %v", errs)
+	}
+}
+
+func TestDebugPanic(t *testing.T) {
+	var sample Sample
+	cache := slabgo.NewCache(sample, slabgo.CacheOptions{Debug: true, Concurrent: true})
+
+	s := cache.Alloc().(*Sample)
+	cache.Free(s)
+	s.id = 1
+
+	func() {
+		defer func() {
+			if err, ok := recover().(error); !ok || !errors.Is(err, slabgo.ErrUseAfterFree) {
+				t.Errorf("Alloc() - expected panic [%v], actual [%v]", slabgo.ErrUseAfterFree, err)
+			}
+		}()
+		cache.Alloc()
+	}()
+
+	// lock is released after panic
+	if cache.Alloc() == nil {
+		t.Error("Alloc() - failed after panic")
+	}
+	checkStats(t, "panic", cache, &slabgo.CacheStats{
+		TotalSlabs: 1,
+		InuseSlabs: 1,
+		TotalObjs:  cache.ObjectLen(),
+		InuseObjs:  2,
+		Allocs:     3,
+		Frees:      1,
+	})
+}
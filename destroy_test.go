@@ -0,0 +1,110 @@
+package slabgo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/k-sone/slabgo"
+)
+
+var errBusy = errors.New("busy")
+
+type destructed struct {
+	all, inuse int
+}
+
+func (d *destructed) destructor(objp interface{}, inuse bool) error {
+	d.all++
+	if inuse {
+		d.inuse++
+		return errBusy
+	}
+	return nil
+}
+
+func TestDestroyInUse(t *testing.T) {
+	var foo Foo
+
+	objLen := 8
+	for _, opts := range []slabgo.CacheOptions{
+		{ObjLen: objLen},
+		{ObjLen: objLen, Concurrent: true, MagazineSize: -1}, // magazines allocate objects ahead
+	} {
+		var d destructed
+		opts.DestructorE = d.destructor
+		cache := slabgo.NewCache(foo, opts)
+
+		var objs []interface{}
+		for i := 0; i < 3; i++ {
+			objs = append(objs, cache.Alloc())
+		}
+		cache.Free(objs[1])
+
+		err := cache.Destroy()
+		if !errors.Is(err, slabgo.ErrInUse) || !errors.Is(err, errBusy) {
+			t.Errorf("Destroy() - expected [%v] and [%v], actual [%v]", slabgo.ErrInUse, errBusy, err)
+		}
+		// objects never allocated are not destructed
+		if d.all != 3 || d.inuse != 2 {
+			t.Errorf("Destroy() - destructed all, inuse: expected [3, 2], actual [%d, %d]", d.all, d.inuse)
+		}
+		checkStats(t, "Destroy()", cache, &slabgo.CacheStats{})
+	}
+}
+
+func TestDestroyStrict(t *testing.T) {
+	objLen := 8
+	for _, opts := range []slabgo.CacheOptions{{ObjLen: objLen}, {ObjLen: objLen, Concurrent: true}} {
+		var d destructed
+		cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{
+			CacheOptions: opts,
+			Constructor:  func(f *Foo) {},
+			DestructorE:  func(f *Foo, inuse bool) error { return d.destructor(f, inuse) },
+		})
+
+		f := cache.Alloc()
+		if err := cache.DestroyStrict(); !errors.Is(err, slabgo.ErrInUse) {
+			t.Errorf("DestroyStrict() - expected [%v], actual [%v]", slabgo.ErrInUse, err)
+		}
+		if d.all != 0 || cache.Alloc() == nil {
+			t.Error("DestroyStrict() - destroyed in use")
+		}
+
+		cache.Free(f)
+		if err := cache.DestroyStrict(); !errors.Is(err, slabgo.ErrInUse) {
+			t.Errorf("DestroyStrict() - expected [%v], actual [%v]", slabgo.ErrInUse, err)
+		}
+		cache.Range(func(f *Foo) bool {
+			cache.Free(f)
+			return true
+		})
+		var stats slabgo.CacheStats
+		cache.ReadStats(&stats)
+		if err := cache.DestroyStrict(); err != nil {
+			t.Errorf("DestroyStrict() - failed: %v", err)
+		}
+		// constructed objects are destructed
+		if d.all != stats.TotalObjs || d.inuse != 0 {
+			t.Errorf("DestroyStrict() - destructed all, inuse: expected [%d, 0], actual [%d, %d]", stats.TotalObjs, d.all, d.inuse)
+		}
+		if cache.Alloc() != nil {
+			t.Error("Alloc() - succeeded after DestroyStrict()")
+		}
+	}
+}
+
+func TestDestroyTypedDestructorE(t *testing.T) {
+	var inuse []int64
+	cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{
+		DestructorE: func(f *Foo, used bool) error {
+			if used {
+				inuse = append(inuse, f.count)
+			}
+			return nil
+		},
+	})
+	cache.Alloc().count = 1
+	if err := cache.Destroy(); !errors.Is(err, slabgo.ErrInUse) || len(inuse) != 1 || inuse[0] != 1 {
+		t.Errorf("Destroy() - unexpected error [%v] or objects %v", err, inuse)
+	}
+}
@@ -0,0 +1,8 @@
+package slabgo
+
+// PinMagazine locks i-th magazine of a concurrent cache, so that other goroutines use the rest
+func PinMagazine(c *Cache, i int) (unpin func()) {
+	m := &c.mags[i]
+	m.mu.Lock()
+	return m.mu.Unlock
+}
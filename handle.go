@@ -0,0 +1,167 @@
+package slabgo
+
+import (
+	"errors"
+	"math/bits"
+	"sync/atomic"
+)
+
+// ErrStaleHandle is returned by `Cache.FreeHandle` if a handle does not point an object in use
+var ErrStaleHandle = errors.New("slabgo: handle is stale or invalid")
+
+// Handle is a compact reference of an object, instead of a pointer.
+// It consists of slab id, slot index within the slab and generation of the slot,
+// so that a handle is detected as stale after the object is freed and reused.
+//
+// NOTE: Generation is 8 bits, so a handle may be valid again after its slot is reused 256 times.
+// The zero Handle is never valid.
+type Handle uint32
+
+const genBits = 8
+
+// slab id and generations of its slots, generations survive the slab destroyed
+type slabID struct {
+	slab *slab
+	gens []uint8
+}
+
+// set bit layout of handles
+func (c *Cache) initHandles() {
+	c.slotBits = uint(bits.Len(uint(c.objLen - 1)))
+	if idBits := 32 - genBits - int(c.slotBits); idBits > 0 {
+		c.maxID = 1<<uint(idBits) - 1
+	}
+	c.ids = make([]slabID, 1) // id 0 is reserved
+}
+
+// give an id to a slab, slabs beyond the limit of ids have no id
+func (c *Cache) assignID(s *slab) {
+	if n := len(c.freeIDs); n > 0 {
+		s.id = c.freeIDs[n-1]
+		c.freeIDs = c.freeIDs[:n-1]
+	} else if len(c.ids) <= c.maxID {
+		s.id = uint32(len(c.ids))
+		c.ids = append(c.ids, slabID{gens: make([]uint8, s.total)})
+	} else {
+		return
+	}
+	c.ids[s.id].slab = s
+	s.gens = c.ids[s.id].gens
+}
+
+// give a specific id to a slab if it is free, otherwise any id
+func (c *Cache) claimID(s *slab, id uint32) {
+	if id == 0 || int(id) > c.maxID {
+		c.assignID(s)
+		return
+	}
+	for len(c.ids) <= int(id) {
+		c.freeIDs = append(c.freeIDs, uint32(len(c.ids)))
+		c.ids = append(c.ids, slabID{gens: make([]uint8, s.total)})
+	}
+	for i, free := range c.freeIDs {
+		if free == id {
+			c.freeIDs = append(c.freeIDs[:i], c.freeIDs[i+1:]...)
+			s.id = id
+			c.ids[id].slab = s
+			s.gens = c.ids[id].gens
+			return
+		}
+	}
+	c.assignID(s)
+}
+
+func (c *Cache) releaseID(s *slab) {
+	if s.id > 0 {
+		c.ids[s.id].slab = nil
+		c.freeIDs = append(c.freeIDs, s.id)
+	}
+}
+
+// return a slab and slot index that `h` points, or nil if `h` is stale
+func (c *Cache) slot(h Handle) (*slab, int) {
+	id := int(h >> (genBits + c.slotBits))
+	if id == 0 || id >= len(c.ids) || c.ids[id].slab == nil {
+		return nil, -1
+	}
+	s := c.ids[id].slab
+	i := int(h>>genBits) & (1<<c.slotBits - 1)
+	if i >= s.total || s.bufctl[i>>3]&(1<<uint(i&0x7)) == 0 || s.gens[i] != uint8(h) ||
+		(s.owned != nil && !s.isOwned(i)) {
+		return nil, -1
+	}
+	return s, i
+}
+
+// Allocate an object from cache, and return a handle of it.
+// return zero Handle and nil if there is no available object.
+// An object allocated by this must be freed by `Cache.FreeHandle`.
+//
+// NOTE: This function bypasses magazines of a concurrent cache.
+func (c *Cache) AllocHandle() (h Handle, obj interface{}) {
+	c.lock()
+	h, obj = c.allocHandle()
+	errs := c.takeCorruptions()
+	c.unlock()
+
+	c.report(errs)
+	if obj == nil {
+		atomic.AddUint64(&c.failed, 1)
+		return
+	}
+	if c.prepares {
+		c.prepare(obj)
+	}
+	return
+}
+
+func (c *Cache) allocHandle() (Handle, interface{}) {
+	obj := c.alloc()
+	if obj == nil {
+		return 0, nil
+	}
+
+	ptr := addrOf(obj)
+	s := c.addrs.get(ptr)
+	i, _ := s.indexOf(ptr)
+	if s.id == 0 {
+		// no more ids, the object is not counted
+		c.release(ptr, false)
+		c.allocs--
+		c.frees--
+		return 0, nil
+	}
+	s.own(i)
+	return Handle(s.id)<<(genBits+c.slotBits) | Handle(i)<<genBits | Handle(s.gens[i]), obj
+}
+
+// Return an object that `h` points, or nil if `h` is stale
+func (c *Cache) Deref(h Handle) interface{} {
+	c.lock()
+	defer c.unlock()
+	if s, i := c.slot(h); s != nil {
+		return s.chunk[i]
+	}
+	return nil
+}
+
+// Return an object that `h` points to cache.
+// return ErrStaleHandle if `h` is stale.
+func (c *Cache) FreeHandle(h Handle) error {
+	c.lock()
+	err := ErrStaleHandle
+	if s, i := c.slot(h); s != nil {
+		err = c.free(s.smem + uintptr(i)*s.objsize)
+	}
+	errs := c.takeCorruptions()
+	c.unlock()
+
+	c.report(errs)
+	if err != nil {
+		return c.freeFailed(uintptr(h), err)
+	}
+	if atomic.LoadInt32(&c.waiters) > 0 {
+		c.wakeup()
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package slabgo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/k-sone/slabgo"
+)
+
+func TestHandleAllocFree(t *testing.T) {
+	objLen := 8
+	for _, opts := range []slabgo.CacheOptions{{ObjLen: objLen}, {ObjLen: objLen, Concurrent: true}} {
+		cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{CacheOptions: opts})
+
+		handles := make([]slabgo.Handle, objLen*2)
+		for i := range handles {
+			h, f := cache.AllocHandle()
+			if f == nil || h == 0 {
+				t.Fatalf("AllocHandle() - failed at %d", i)
+			}
+			f.count = int64(i)
+			handles[i] = h
+		}
+		for i, h := range handles {
+			if f := cache.Deref(h); f == nil || f.count != int64(i) {
+				t.Errorf("Deref() - unexpected object at %d", i)
+			}
+		}
+
+		for i, h := range handles[:objLen+1] {
+			if err := cache.FreeHandle(h); err != nil {
+				t.Errorf("FreeHandle() - failed at %d: %v", i, err)
+			}
+		}
+		checkStats(t, "FreeHandle()", cache.Cache(), &slabgo.CacheStats{
+			TotalSlabs: 2,
+			InuseSlabs: 1,
+			TotalObjs:  objLen * 2,
+			InuseObjs:  objLen - 1,
+			Allocs:     uint64(objLen * 2),
+			Frees:      uint64(objLen + 1),
+		})
+
+		// stale handles
+		for i, h := range handles[:objLen+1] {
+			if f := cache.Deref(h); f != nil {
+				t.Errorf("Deref() - stale handle at %d", i)
+			}
+			if err := cache.FreeHandle(h); !errors.Is(err, slabgo.ErrStaleHandle) {
+				t.Errorf("FreeHandle() - expected [%v], actual [%v]", slabgo.ErrStaleHandle, err)
+			}
+		}
+
+		// reused slot has a new handle
+		h, f := cache.AllocHandle()
+		if h == handles[0] || cache.Deref(handles[0]) != nil || cache.Deref(h) != f {
+			t.Errorf("AllocHandle() - reused handle %#x", h)
+		}
+
+		if cache.Deref(0) != nil {
+			t.Error("Deref() - zero handle")
+		}
+		cache.Destroy()
+		if cache.Deref(h) != nil {
+			t.Error("Deref() - destroyed")
+		}
+	}
+}
+
+func TestHandleDestroyedSlab(t *testing.T) {
+	objLen := 8
+	cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{
+		CacheOptions: slabgo.CacheOptions{
+			ObjLen: objLen,
+			Grower: func(s *slabgo.CacheStats) int { return 1 },
+			Reaper: func(s *slabgo.CacheStats) int { return 1 },
+		},
+	})
+
+	// slab id is reused by a new slab
+	h, _ := cache.AllocHandle()
+	cache.FreeHandle(h)
+	h2, f := cache.AllocHandle()
+	if h2 == h || cache.Deref(h) != nil || cache.Deref(h2) != f {
+		t.Errorf("AllocHandle() - unexpected handle %#x after %#x", h2, h)
+	}
+	checkGrowReap(t, "handle", cache.Cache(), 2, 1)
+
+	// objects allocated by pointer can be freed by pointer
+	p := cache.Alloc()
+	if !cache.Free(p) {
+		t.Error("Free() - failed")
+	}
+}
@@ -0,0 +1,43 @@
+package slabgo
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// clear an object, and call hooks before it is returned by Alloc
+func (c *Cache) prepare(obj interface{}) {
+	if c.zero {
+		c.clear(obj)
+	}
+	if c.reset != nil && !c.resetOnFree {
+		c.reset(obj)
+	}
+	if c.onAlloc != nil {
+		c.onAlloc(obj)
+	}
+}
+
+// set zero value to an object
+func (c *Cache) clear(obj interface{}) {
+	v := reflect.ValueOf(obj)
+	if c.zeroPtrs {
+		// clear with write barriers
+		v.Elem().SetZero()
+	} else {
+		mem := unsafe.Slice((*byte)(v.UnsafePointer()), c.objType.Size())
+		for i := range mem {
+			mem[i] = 0
+		}
+	}
+}
+
+// call hooks when an object is freed
+func (c *Cache) freed(obj interface{}) {
+	if c.onFree != nil {
+		c.onFree(obj)
+	}
+	if c.resetOnFree {
+		c.reset(obj)
+	}
+}
@@ -0,0 +1,77 @@
+package slabgo_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+type refCounted struct {
+	refs int
+}
+
+func TestHookAllocFree(t *testing.T) {
+	var obj refCounted
+
+	objLen := 8
+	for _, opts := range []slabgo.CacheOptions{
+		{ObjLen: objLen},
+		{ObjLen: objLen, Concurrent: true},
+		{ObjLen: objLen, Concurrent: true, MagazineSize: 2},
+		{ObjLen: objLen, Debug: true, ZeroOnAlloc: true},
+	} {
+		var allocs, frees int
+		var events []string
+		opts.Reset = func(objp interface{}) { events = append(events, "reset") }
+		opts.OnAlloc = func(objp interface{}) {
+			r := objp.(*refCounted)
+			if r.refs != 0 {
+				t.Errorf("OnAlloc() - refs %d", r.refs)
+			}
+			r.refs = 1
+			allocs++
+		}
+		opts.OnFree = func(objp interface{}) {
+			r := objp.(*refCounted)
+			if r.refs != 1 {
+				t.Errorf("OnFree() - refs %d", r.refs)
+			}
+			r.refs = 0
+			frees++
+		}
+		cache := slabgo.NewCache(obj, opts)
+
+		objs := make([]interface{}, objLen*2)
+		for i := range objs[:objLen] {
+			objs[i] = cache.Alloc()
+		}
+		if n := cache.AllocN(objs[objLen:]); n != objLen {
+			t.Fatalf("AllocN() - failed %d", n)
+		}
+		if len(events) != objLen*2 || events[0] != "reset" {
+			t.Errorf("Alloc() - unexpected events %v", events)
+		}
+
+		ptrs := make([]unsafe.Pointer, objLen)
+		for i, o := range objs[objLen:] {
+			ptrs[i] = unsafe.Pointer(o.(*refCounted))
+		}
+		for _, o := range objs[:objLen] {
+			cache.Free(o)
+		}
+		cache.FreeN(ptrs)
+
+		// invalid free is not notified
+		cache.Free(&obj)
+
+		// hooks are called once per transition, though objects move between magazines and slabs
+		cache.Shrink()
+		h, _ := cache.AllocHandle()
+		cache.FreeHandle(h)
+		if allocs != objLen*2+1 || frees != objLen*2+1 {
+			t.Errorf("hooks - allocs, frees: expected [%d, %d], actual [%d, %d]", objLen*2+1, objLen*2+1, allocs, frees)
+		}
+		cache.Destroy()
+	}
+}
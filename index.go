@@ -0,0 +1,121 @@
+package slabgo
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// intrusive doubly linked list of slabs
+type slabList struct {
+	head *slab
+	tail *slab
+	len  int
+}
+
+// add a slab to the front of list
+func (l *slabList) push(s *slab) {
+	s.list = l
+	s.prev = nil
+	s.next = l.head
+	if l.head != nil {
+		l.head.prev = s
+	} else {
+		l.tail = s
+	}
+	l.head = s
+	l.len++
+}
+
+// remove a slab from list
+func (l *slabList) remove(s *slab) *slab {
+	if s.prev != nil {
+		s.prev.next = s.next
+	} else {
+		l.head = s.next
+	}
+	if s.next != nil {
+		s.next.prev = s.prev
+	} else {
+		l.tail = s.prev
+	}
+	s.list, s.prev, s.next = nil, nil, nil
+	l.len--
+	return s
+}
+
+// map from address to slab.
+// Address space is divided into granules larger than a slab,
+// so that a granule is covered by at most three slabs.
+type slabIndex struct {
+	shift  uint
+	m      map[uintptr][3]*slab
+	shared *sync.Map // copy of m updated by granule, for lookup without lock (magazine mode only)
+}
+
+// create an index of slabs whose object array is `size` bytes
+func newSlabIndex(size uintptr) slabIndex {
+	return slabIndex{
+		shift: uint(bits.Len64(uint64(size - 1))),
+		m:     make(map[uintptr][3]*slab),
+	}
+}
+
+func (x *slabIndex) add(s *slab) {
+	for k := s.smem >> x.shift; k <= s.last()>>x.shift; k++ {
+		e := x.m[k]
+		for i := range e {
+			if e[i] == nil {
+				e[i] = s
+				break
+			}
+		}
+		x.m[k] = e
+		if x.shared != nil {
+			x.shared.Store(k, e)
+		}
+	}
+}
+
+func (x *slabIndex) remove(s *slab) {
+	for k := s.smem >> x.shift; k <= s.last()>>x.shift; k++ {
+		e := x.m[k]
+		for i := range e {
+			if e[i] == s {
+				e[i] = nil
+			}
+		}
+		if e == [3]*slab{} {
+			delete(x.m, k)
+			if x.shared != nil {
+				x.shared.Delete(k)
+			}
+		} else {
+			x.m[k] = e
+			if x.shared != nil {
+				x.shared.Store(k, e)
+			}
+		}
+	}
+}
+
+// return a slab that `p` points within, or nil
+func (x *slabIndex) get(p uintptr) *slab {
+	for _, s := range x.m[p>>x.shift] {
+		if s != nil && s.owns(p) {
+			return s
+		}
+	}
+	return nil
+}
+
+// return a slab that `p` points within without lock, or nil
+func (x *slabIndex) load(p uintptr) *slab {
+	if e, ok := x.shared.Load(p >> x.shift); ok {
+		for _, s := range e.([3]*slab) {
+			if s != nil && s.owns(p) {
+				return s
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Package metrics exports statistics of slabgo caches via expvar and
+// Prometheus text format.
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/k-sone/slabgo"
+)
+
+// ErrDuplicated is returned by `Registry.Register` if a name is already registered
+var ErrDuplicated = errors.New("metrics: name is already registered")
+
+// StatsReader is a source of cache statistics.
+// It is implemented by *slabgo.Cache and *slabgo.TypedCache.
+//
+// NOTE: ReadStats is called from other goroutines, so caches must be created with
+// `slabgo.CacheOptions.Concurrent`.
+type StatsReader interface {
+	ReadStats(s *slabgo.CacheStats)
+}
+
+// Set of named caches
+type Registry struct {
+	mu     sync.RWMutex
+	caches map[string]StatsReader
+}
+
+// Create an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{caches: make(map[string]StatsReader)}
+}
+
+// Default registry used by package level functions
+var DefaultRegistry = NewRegistry()
+
+// Register a cache with `name`
+func (r *Registry) Register(name string, c StatsReader) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.caches[name]; ok {
+		return ErrDuplicated
+	}
+	r.caches[name] = c
+	return nil
+}
+
+// Unregister a cache with `name`
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.caches, name)
+}
+
+// Return statistics of all caches by name
+func (r *Registry) Snapshot() map[string]slabgo.CacheStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make(map[string]slabgo.CacheStats, len(r.caches))
+	for name, c := range r.caches {
+		var s slabgo.CacheStats
+		c.ReadStats(&s)
+		stats[name] = s
+	}
+	return stats
+}
+
+// Return statistics of all caches as JSON, this implements expvar.Var
+func (r *Registry) String() string {
+	b, err := json.Marshal(r.Snapshot())
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// Publish a registry to expvar with `name`.
+// It panics if `name` is already published, as same as expvar.Publish.
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, r)
+}
+
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value func(s *slabgo.CacheStats) float64
+}
+
+var metrics = []metric{
+	{"slabgo_total_slabs", "gauge", "Number of slabs.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.TotalSlabs) }},
+	{"slabgo_inuse_slabs", "gauge", "Number of slabs in use.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.InuseSlabs) }},
+	{"slabgo_total_objs", "gauge", "Number of objects.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.TotalObjs) }},
+	{"slabgo_inuse_objs", "gauge", "Number of objects in use.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.InuseObjs) }},
+	{"slabgo_allocs_total", "counter", "Number of allocs.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.Allocs) }},
+	{"slabgo_frees_total", "counter", "Number of frees.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.Frees) }},
+	{"slabgo_cache_size_bytes", "gauge", "Bytes of cache size.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.CacheSize) }},
+	{"slabgo_cache_size_inuse_bytes", "gauge", "Bytes of cache size in use.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.CacheSizeInuse) }},
+	{"slabgo_grows_total", "counter", "Number of times slabs are added by grower.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.Grows) }},
+	{"slabgo_reaps_total", "counter", "Number of times slabs are removed by reaper.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.Reaps) }},
+	{"slabgo_peak_inuse_objs", "gauge", "Maximum number of objects in use.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.PeakInuseObjs) }},
+	{"slabgo_peak_total_slabs", "gauge", "Maximum number of slabs.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.PeakTotalSlabs) }},
+	{"slabgo_partial_slabs", "gauge", "Number of slabs that have both used and free objects.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.PartialSlabs) }},
+	{"slabgo_fragmentation_ratio", "gauge", "Ratio of free objects within slabs in use.",
+		func(s *slabgo.CacheStats) float64 { return s.Fragmentation }},
+	{"slabgo_slabs_created_total", "counter", "Number of slabs created.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.SlabsCreated) }},
+	{"slabgo_slabs_destroyed_total", "counter", "Number of slabs destroyed.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.SlabsDestroyed) }},
+	{"slabgo_failed_allocs_total", "counter", "Number of allocs that returned no object.",
+		func(s *slabgo.CacheStats) float64 { return float64(s.FailedAllocs) }},
+}
+
+// Write statistics of all caches in Prometheus text format
+func (r *Registry) WriteText(w io.Writer) error {
+	stats := r.Snapshot()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind); err != nil {
+			return err
+		}
+		for _, name := range names {
+			s := stats[name]
+			if _, err := fmt.Fprintf(w, "%s{cache=\"%s\"} %s\n", m.name, escape(name),
+				strconv.FormatFloat(m.value(&s), 'g', -1, 64)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Serve statistics of all caches in Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteText(w)
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape a label value
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// Register a cache with `name` to the default registry
+func Register(name string, c StatsReader) error {
+	return DefaultRegistry.Register(name, c)
+}
+
+// Unregister a cache with `name` from the default registry
+func Unregister(name string) {
+	DefaultRegistry.Unregister(name)
+}
+
+// Return a handler that serves the default registry in Prometheus text format
+func Handler() http.Handler {
+	return DefaultRegistry
+}
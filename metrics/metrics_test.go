@@ -0,0 +1,136 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/k-sone/slabgo"
+	"github.com/k-sone/slabgo/metrics"
+)
+
+type Foo struct {
+	name  string
+	count int64
+}
+
+func newCache(t *testing.T, n int) *slabgo.Cache {
+	var foo Foo
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, Concurrent: true, MagazineSize: -1})
+	for i := 0; i < n; i++ {
+		if cache.Alloc() == nil {
+			t.Fatalf("Alloc() - failed at %d", i)
+		}
+	}
+	return cache
+}
+
+func TestRegister(t *testing.T) {
+	r := metrics.NewRegistry()
+	cache := newCache(t, 3)
+	if err := r.Register("foo", cache); err != nil {
+		t.Errorf("Register() - failed: %v", err)
+	}
+	if err := r.Register("foo", cache); !errors.Is(err, metrics.ErrDuplicated) {
+		t.Errorf("Register() - expected [%v], actual [%v]", metrics.ErrDuplicated, err)
+	}
+
+	stats := r.Snapshot()
+	if s, ok := stats["foo"]; !ok || s.InuseObjs != 3 || s.Grows != 1 {
+		t.Errorf("Snapshot() - unexpected %+v", stats)
+	}
+
+	r.Unregister("foo")
+	if stats := r.Snapshot(); len(stats) != 0 {
+		t.Errorf("Unregister() - unexpected %+v", stats)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.Register("foo", newCache(t, 10))
+	r.Register(`bar"\`, slabgo.NewTypedCacheSimple[Foo]())
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	res, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET - failed: %v", err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("GET - content type %q", ct)
+	}
+	b, _ := io.ReadAll(res.Body)
+	body := string(b)
+
+	for _, line := range []string{
+		"# HELP slabgo_inuse_objs Number of objects in use.",
+		"# TYPE slabgo_inuse_objs gauge",
+		"# TYPE slabgo_allocs_total counter",
+		`slabgo_total_slabs{cache="foo"} 2`,
+		`slabgo_inuse_slabs{cache="foo"} 2`,
+		`slabgo_total_objs{cache="foo"} 16`,
+		`slabgo_inuse_objs{cache="foo"} 10`,
+		`slabgo_allocs_total{cache="foo"} 10`,
+		`slabgo_frees_total{cache="foo"} 0`,
+		`slabgo_cache_size_bytes{cache="foo"} 384`,
+		`slabgo_cache_size_inuse_bytes{cache="foo"} 240`,
+		`slabgo_grows_total{cache="foo"} 2`,
+		`slabgo_reaps_total{cache="foo"} 0`,
+		`slabgo_peak_inuse_objs{cache="foo"} 10`,
+		`slabgo_fragmentation_ratio{cache="foo"} 0.375`,
+		`slabgo_slabs_created_total{cache="foo"} 2`,
+		`slabgo_total_slabs{cache="bar\"\\"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("GET - %q not found in\n%s", line, body)
+		}
+	}
+	if i, j := strings.Index(body, `{cache="bar`), strings.Index(body, `{cache="foo"}`); i > j {
+		t.Errorf("GET - caches are not sorted\n%s", body)
+	}
+}
+
+// number of runs of TestExpvar, names can not be published twice within a process
+var expvarRuns int
+
+func TestExpvar(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.Register("foo", newCache(t, 5))
+	expvarRuns++
+	name := fmt.Sprintf("slabgo_test_%d", expvarRuns)
+	r.Publish(name)
+
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatal("Publish() - not published")
+	}
+	var stats map[string]slabgo.CacheStats
+	if err := json.Unmarshal([]byte(v.String()), &stats); err != nil {
+		t.Fatalf("String() - invalid json: %v", err)
+	}
+	if s := stats["foo"]; s.InuseObjs != 5 || s.TotalSlabs != 1 {
+		t.Errorf("String() - unexpected %+v", stats)
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	cache := newCache(t, 1)
+	if err := metrics.Register("default", cache); err != nil {
+		t.Fatalf("Register() - failed: %v", err)
+	}
+	defer metrics.Unregister("default")
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if body := w.Body.String(); !strings.Contains(body, `slabgo_inuse_objs{cache="default"} 1`) {
+		t.Errorf("Handler() - unexpected\n%s", body)
+	}
+}
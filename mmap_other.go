@@ -0,0 +1,25 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package slabgo
+
+import (
+	"os"
+)
+
+const offHeapSupported = false
+
+func mmap(size int) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func mmapFile(f *os.File, off int64, size int) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func munmap(mem []byte) error {
+	return ErrNotSupported
+}
+
+func lockFile(f *os.File) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,34 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package slabgo
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const offHeapSupported = true
+
+// map anonymous memory outside of Go heap
+func mmap(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+}
+
+// map a region of file, changes are written back to the file
+func mmapFile(f *os.File, off int64, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), off, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+func munmap(mem []byte) error {
+	return syscall.Munmap(mem)
+}
+
+// lock file exclusively until it is closed, return ErrFileLocked if it is locked by another
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrFileLocked
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package slabgo_test
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+type Record struct {
+	ts    int64
+	value float64
+	tags  [4]uint16
+}
+
+func TestOffHeapPointers(t *testing.T) {
+	var foo Foo
+	if _, err := slabgo.NewCacheE(foo, slabgo.CacheOptions{OffHeap: true}); !errors.Is(err, slabgo.ErrInvalidOptions) {
+		t.Errorf("NewCacheE() - expected [%v], actual [%v]", slabgo.ErrInvalidOptions, err)
+	}
+	if _, err := slabgo.NewCacheE(Record{}, slabgo.CacheOptions{OffHeap: true}); err != nil {
+		t.Errorf("NewCacheE() - unexpected error [%v]", err)
+	}
+}
+
+func TestOffHeap(t *testing.T) {
+	objLen := 8
+	for _, opts := range []slabgo.CacheOptions{
+		{ObjLen: objLen},
+		{ObjLen: objLen, Debug: true},
+		{ObjLen: objLen, Concurrent: true, MagazineSize: -1},
+	} {
+		destructed := 0
+		opts.OffHeap = true
+		opts.Grower = func(s *slabgo.CacheStats) int { return 1 }
+		opts.Reaper = func(s *slabgo.CacheStats) int { return s.TotalSlabs - s.InuseSlabs }
+		opts.Destructor = func(objp interface{}) { destructed++ }
+		cache := slabgo.NewCache(Record{}, opts)
+
+		var objs []*Record
+		for i := 0; i < objLen*3; i++ {
+			s := cache.Alloc().(*Record)
+			s.ts = int64(i + 1)
+			s.value = float64(s.ts) / 2
+			s.tags[3] = uint16(i)
+			objs = append(objs, s)
+		}
+		runtime.GC()
+		for i, s := range objs {
+			if s.ts != int64(i+1) || s.value != float64(s.ts)/2 || s.tags[3] != uint16(i) {
+				t.Errorf("Alloc() - object %d is modified %+v", i, *s)
+			}
+		}
+		checkStats(t, "Alloc()", cache, &slabgo.CacheStats{
+			TotalSlabs: 3, InuseSlabs: 3, TotalObjs: objLen * 3, InuseObjs: objLen * 3, Allocs: uint64(objLen * 3),
+		})
+
+		// the last slab is unmapped by reaper
+		for _, s := range objs[objLen*2:] {
+			if err := cache.FreePtrErr(unsafe.Pointer(s)); err != nil {
+				t.Errorf("FreePtrErr() - unexpected error [%v]", err)
+			}
+		}
+		checkStats(t, "FreePtrErr()", cache, &slabgo.CacheStats{
+			TotalSlabs: 2, InuseSlabs: 2, TotalObjs: objLen * 2, InuseObjs: objLen * 2,
+			Allocs: uint64(objLen * 3), Frees: uint64(objLen),
+		})
+		checkDestruct(t, "FreePtrErr()", destructed, objLen)
+
+		s := cache.Alloc().(*Record)
+		if *s != (Record{}) && !opts.Debug {
+			t.Errorf("Alloc() - new slab is not cleared %+v", *s)
+		}
+		cache.FreePtr(unsafe.Pointer(s))
+		for _, s := range objs[:objLen*2] {
+			cache.FreePtr(unsafe.Pointer(s))
+		}
+		if err := cache.Destroy(); err != nil {
+			t.Errorf("Destroy() - unexpected error [%v]", err)
+		}
+		// objects never allocated in the new slab are not destructed
+		checkDestruct(t, "Destroy()", destructed, objLen*3+1)
+	}
+}
+
+func TestOffHeapTyped(t *testing.T) {
+	cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Record]{
+		CacheOptions: slabgo.CacheOptions{ObjLen: 8, OffHeap: true, Concurrent: true},
+	})
+	s := cache.Alloc()
+	s.ts = 1
+	cache.Free(s)
+	if err := cache.Destroy(); err != nil {
+		t.Errorf("Destroy() - unexpected error [%v]", err)
+	}
+}
@@ -0,0 +1,287 @@
+package slabgo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Errors returned by `OpenPersistentCache`
+var (
+	ErrInvalidFile = errors.New("slabgo: file is not a cache or does not match object")
+	ErrFileLocked  = errors.New("slabgo: file is used by another cache")
+)
+
+const (
+	fileMagic   = "slabgo\x00\x00"
+	fileVersion = 1
+	headerSize  = 52
+)
+
+// file that slabs of a persistent cache are mapped from.
+//
+// Layout of file (integers are little endian):
+//
+//	header (padded to align):
+//	   0 magic     [8]byte
+//	   8 version   uint32
+//	  12 align     uint32 (page size when the file is created)
+//	  16 objsize   uint64
+//	  24 objlen    uint64
+//	  32 slabs     uint64
+//	  40 typehash  uint64 (FNV-1a of layout of object type)
+//	  48 checksum  uint32 (CRC-32 of bytes above)
+//	slab 0 (padded to align):
+//	   0 bufctl    [objlen/8]byte
+//	   n object array, n is aligned to 8 bytes
+//	slab 1 ...
+type slabFile struct {
+	f      *os.File
+	etype  reflect.Type
+	hash   uint64 // hash of layout of etype
+	objLen int
+	align  int // alignment of header and slabs
+	region int // bytes of a slab within file
+	array  int // offset of object array within a slab
+	slabs  int // number of slabs within file
+}
+
+// Open a persistent cache whose slabs are stored in file `path`, the file is created if not exists.
+// Objects in use are restored from the file, so that they survive restart of process.
+// The type of object must not have pointers. Debug, OffHeap and destructors are not supported,
+// and magazines of a concurrent cache are disabled.
+// Allocation sites of objects restored from the file are unknown to `Cache.Leaks`.
+// return ErrInvalidFile if the file does not match object and `opts.ObjLen`,
+// or ErrFileLocked if the file is opened by another cache (advisory lock, the file must not be shared by processes).
+//
+// NOTE: Slabs are never reaped, and the file never shrinks.
+// Objects in use are not reported by `Cache.Destroy`, because they are kept in the file.
+// Changes are written back to the file by OS, `Cache.Sync` flushes them to storage.
+// An object may be inconsistent if the process crashes while updating it.
+func OpenPersistentCache(path string, obj interface{}, opts CacheOptions) (*Cache, error) {
+	if val := reflect.ValueOf(obj); val.IsValid() && hasPointers(val.Type()) ||
+		opts.Debug || opts.OffHeap || opts.Destructor != nil || opts.DestructorE != nil {
+		return nil, ErrInvalidOptions
+	}
+	if !offHeapSupported {
+		return nil, ErrNotSupported
+	}
+
+	opts.MagazineSize = -1
+	c, err := NewCacheE(obj, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.openFile(path); err != nil {
+		c.Destroy()
+		return nil, err
+	}
+	return c, nil
+}
+
+// open a file, and restore slabs from it
+func (c *Cache) openFile(path string) error {
+	c.lock()
+	defer c.unlock()
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return err
+	}
+	sf := &slabFile{f: f, etype: c.objType, hash: typeHash(c.objType), objLen: c.objLen}
+	if err := sf.init(); err != nil {
+		f.Close()
+		return err
+	}
+
+	c.file = sf
+	for i := 0; i < sf.slabs; i++ {
+		s, err := sf.mapSlab(i)
+		if err != nil {
+			return err
+		}
+		// objects may have been allocated
+		s.touchAll()
+		c.initSlab(s)
+		c.addrs.add(s)
+		c.assignID(s)
+		c.restore(s)
+	}
+	return nil
+}
+
+// add a slab to lists, in use state is restored from bufctl
+func (c *Cache) restore(s *slab) {
+	s.inuse = 0
+	s.first = s.total
+	for i := 0; i < s.total; i++ {
+		if s.bufctl[i>>3]&(1<<uint(i&0x7)) != 0 {
+			s.inuse++
+		} else if s.first == s.total {
+			s.first = i
+		}
+	}
+	switch s.inuse {
+	case 0:
+		c.toEmpty(s)
+	case s.total:
+		c.full.push(s)
+	default:
+		c.toPartial(s)
+	}
+
+	c.inuseObjs += s.inuse
+	c.created++
+	if c.inuseObjs > c.peakObjs {
+		c.peakObjs = c.inuseObjs
+	}
+	if total := c.full.len + c.partial.len + c.empty.len; total > c.peakSlabs {
+		c.peakSlabs = total
+	}
+}
+
+// Flush changes of objects to storage.
+// return nil if cache is not persistent.
+func (c *Cache) Sync() error {
+	c.lock()
+	defer c.unlock()
+	if c.file == nil {
+		return nil
+	}
+	return c.file.f.Sync()
+}
+
+// read header, or write it if file is empty
+func (sf *slabFile) init() error {
+	fi, err := sf.f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() == 0 {
+		sf.align = os.Getpagesize()
+		sf.layout()
+		if err := sf.f.Truncate(int64(sf.align)); err != nil {
+			return err
+		}
+		return sf.writeHeader()
+	}
+
+	var h [headerSize]byte
+	if _, err := sf.f.ReadAt(h[:], 0); err == io.EOF {
+		return ErrInvalidFile
+	} else if err != nil {
+		return err
+	}
+	le := binary.LittleEndian
+	if string(h[:8]) != fileMagic || le.Uint32(h[48:]) != crc32.ChecksumIEEE(h[:48]) ||
+		le.Uint32(h[8:]) != fileVersion || le.Uint64(h[16:]) != uint64(sf.etype.Size()) ||
+		le.Uint64(h[24:]) != uint64(sf.objLen) || le.Uint64(h[40:]) != sf.hash {
+		return ErrInvalidFile
+	}
+	sf.align = int(le.Uint32(h[12:]))
+	sf.slabs = int(le.Uint64(h[32:]))
+	if sf.align <= 0 || sf.align%os.Getpagesize() != 0 {
+		// slabs can not be mapped on this platform
+		return ErrInvalidFile
+	}
+	sf.layout()
+	if size := int64(sf.align) + int64(sf.slabs)*int64(sf.region); fi.Size() < size {
+		return ErrInvalidFile
+	} else if fi.Size() > size {
+		// drop a slab that failed to be added
+		return sf.f.Truncate(size)
+	}
+	return nil
+}
+
+// calculate offsets of slabs within file
+func (sf *slabFile) layout() {
+	sf.array = (sf.objLen>>3 + 7) &^ 7
+	size := sf.array + sf.objLen*int(sf.etype.Size())
+	sf.region = (size + sf.align - 1) / sf.align * sf.align
+}
+
+func (sf *slabFile) writeHeader() error {
+	var h [headerSize]byte
+	le := binary.LittleEndian
+	copy(h[:8], fileMagic)
+	le.PutUint32(h[8:], fileVersion)
+	le.PutUint32(h[12:], uint32(sf.align))
+	le.PutUint64(h[16:], uint64(sf.etype.Size()))
+	le.PutUint64(h[24:], uint64(sf.objLen))
+	le.PutUint64(h[32:], uint64(sf.slabs))
+	le.PutUint64(h[40:], sf.hash)
+	le.PutUint32(h[48:], crc32.ChecksumIEEE(h[:48]))
+	_, err := sf.f.WriteAt(h[:], 0)
+	return err
+}
+
+// map i-th slab within file
+func (sf *slabFile) mapSlab(i int) (*slab, error) {
+	mem, err := mmapFile(sf.f, int64(sf.align)+int64(i)*int64(sf.region), sf.region)
+	if err != nil {
+		return nil, err
+	}
+	size := sf.objLen * int(sf.etype.Size())
+	s := slabOf(sf.etype, sf.objLen, mem[sf.array:sf.array+size], false)
+	s.bufctl = mem[:sf.objLen>>3]
+	s.mapping = mem
+	return s, nil
+}
+
+// extend file by a slab, return nil if file can not be extended
+func (sf *slabFile) newSlab(ctor Constructor) *slab {
+	if err := sf.f.Truncate(int64(sf.align) + int64(sf.slabs+1)*int64(sf.region)); err != nil {
+		return nil
+	}
+	s, err := sf.mapSlab(sf.slabs)
+	if err != nil {
+		return nil
+	}
+	sf.slabs++
+	if err := sf.writeHeader(); err != nil {
+		sf.slabs--
+		munmap(s.mapping)
+		return nil
+	}
+	s.construct(ctor)
+	return s
+}
+
+func (sf *slabFile) close() error {
+	return sf.f.Close()
+}
+
+// return a hash of layout of type, which consists of kinds, sizes, names and offsets of fields
+func typeHash(t reflect.Type) uint64 {
+	h := fnv.New64a()
+	writeLayout(h, t)
+	return h.Sum64()
+}
+
+func writeLayout(w io.Writer, t reflect.Type) {
+	fmt.Fprintf(w, "%s:%d", t.Kind(), t.Size())
+	switch t.Kind() {
+	case reflect.Array:
+		fmt.Fprintf(w, "[%d]", t.Len())
+		writeLayout(w, t.Elem())
+	case reflect.Struct:
+		io.WriteString(w, "{")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fmt.Fprintf(w, "%s@%d ", f.Name, f.Offset)
+			writeLayout(w, f.Type)
+			io.WriteString(w, ";")
+		}
+		io.WriteString(w, "}")
+	}
+}
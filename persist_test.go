@@ -0,0 +1,169 @@
+package slabgo_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+// same size as Record, but a different type
+type Measure struct {
+	ts    float64
+	value float64
+	tags  [4]uint16
+}
+
+func TestPersistentRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.slab")
+	objLen := 8
+	for _, opts := range []slabgo.CacheOptions{{ObjLen: objLen}, {ObjLen: objLen, Concurrent: true}} {
+		opts.Grower = func(s *slabgo.CacheStats) int { return 1 }
+		os.Remove(path)
+		cache, err := slabgo.OpenPersistentCache(path, Record{}, opts)
+		if err != nil {
+			t.Fatalf("OpenPersistentCache() - unexpected error [%v]", err)
+		}
+
+		// full, partial and empty slab
+		var objs []*Record
+		for i := 0; i < objLen*3; i++ {
+			r := cache.Alloc().(*Record)
+			r.ts = int64(i)
+			objs = append(objs, r)
+		}
+		for i, r := range objs[objLen:] {
+			if i < objLen && i%2 == 0 {
+				continue
+			}
+			cache.FreePtr(unsafe.Pointer(r))
+		}
+		if err := cache.Sync(); err != nil {
+			t.Errorf("Sync() - unexpected error [%v]", err)
+		}
+		if err := cache.Destroy(); err != nil {
+			t.Errorf("Destroy() - unexpected error [%v]", err)
+		}
+
+		cache, err = slabgo.OpenPersistentCache(path, Record{}, opts)
+		if err != nil {
+			t.Fatalf("OpenPersistentCache() - unexpected error [%v]", err)
+		}
+		checkStats(t, "OpenPersistentCache()", cache, &slabgo.CacheStats{
+			TotalSlabs: 3, InuseSlabs: 2, TotalObjs: objLen * 3, InuseObjs: objLen + objLen/2,
+		})
+		var restored []int64
+		cache.Range(func(objp interface{}) bool {
+			restored = append(restored, objp.(*Record).ts)
+			return true
+		})
+		if len(restored) != objLen+objLen/2 {
+			t.Errorf("Range() - expected [%d] objects, actual %v", objLen+objLen/2, restored)
+		}
+		for _, ts := range restored {
+			if ts >= int64(objLen) && ts%2 != 0 {
+				t.Errorf("Range() - freed object %d is restored", ts)
+			}
+		}
+
+		// freed objects are reused before slabs are added
+		for i := 0; i < objLen*3/2; i++ {
+			if cache.Alloc() == nil {
+				t.Errorf("Alloc() - no object")
+			}
+		}
+		checkStats(t, "Alloc()", cache, &slabgo.CacheStats{
+			TotalSlabs: 3, InuseSlabs: 3, TotalObjs: objLen * 3, InuseObjs: objLen * 3, Allocs: uint64(objLen * 3 / 2),
+		})
+		cache.Alloc()
+		if err := cache.Destroy(); err != nil {
+			t.Errorf("Destroy() - unexpected error [%v]", err)
+		}
+
+		typed, err := slabgo.OpenPersistentTypedCache(path, slabgo.TypedCacheOptions[Record]{CacheOptions: opts})
+		if err != nil {
+			t.Fatalf("OpenPersistentTypedCache() - unexpected error [%v]", err)
+		}
+		var stats slabgo.CacheStats
+		if typed.ReadStats(&stats); stats.TotalSlabs != 4 || stats.InuseObjs != objLen*3+1 {
+			t.Errorf("OpenPersistentTypedCache() - unexpected stats %+v", stats)
+		}
+		typed.Destroy()
+	}
+}
+
+func TestPersistentTrack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.slab")
+	opts := slabgo.CacheOptions{ObjLen: 8, Track: true}
+	cache, err := slabgo.OpenPersistentCache(path, Record{}, opts)
+	if err != nil {
+		t.Fatalf("OpenPersistentCache() - unexpected error [%v]", err)
+	}
+	for i := 0; i < 3; i++ {
+		cache.Alloc()
+	}
+	cache.Destroy()
+
+	cache, err = slabgo.OpenPersistentCache(path, Record{}, opts)
+	if err != nil {
+		t.Fatalf("OpenPersistentCache() - unexpected error [%v]", err)
+	}
+	defer cache.Destroy()
+	if cache.Alloc() == nil {
+		t.Fatal("Alloc() - no object")
+	}
+	// sites of restored objects are unknown
+	leaks := cache.Leaks()
+	if len(leaks) != 2 || leaks[0].Count != 3 || leaks[0].Frames != nil || leaks[1].Count != 1 || len(leaks[1].Frames) == 0 {
+		t.Errorf("Leaks() - unexpected %v", leaks)
+	}
+}
+
+func TestPersistentInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "records.slab")
+	cache, err := slabgo.OpenPersistentCache(path, Record{}, slabgo.CacheOptions{ObjLen: 8})
+	if err != nil {
+		t.Fatalf("OpenPersistentCache() - unexpected error [%v]", err)
+	}
+	cache.Alloc()
+	if _, err := slabgo.OpenPersistentCache(path, Record{}, slabgo.CacheOptions{ObjLen: 8}); !errors.Is(err, slabgo.ErrFileLocked) {
+		t.Errorf("OpenPersistentCache() locked - expected [%v], actual [%v]", slabgo.ErrFileLocked, err)
+	}
+	cache.Destroy()
+
+	for _, c := range []struct {
+		name string
+		obj  interface{}
+		opts slabgo.CacheOptions
+		err  error
+	}{
+		{"pointers", Foo{}, slabgo.CacheOptions{ObjLen: 8}, slabgo.ErrInvalidOptions},
+		{"debug", Record{}, slabgo.CacheOptions{ObjLen: 8, Debug: true}, slabgo.ErrInvalidOptions},
+		{"destructor", Record{}, slabgo.CacheOptions{ObjLen: 8, Destructor: func(interface{}) {}}, slabgo.ErrInvalidOptions},
+		{"size", [4]int64{}, slabgo.CacheOptions{ObjLen: 8}, slabgo.ErrInvalidFile},
+		{"type", Measure{}, slabgo.CacheOptions{ObjLen: 8}, slabgo.ErrInvalidFile},
+		{"array", [3]int64{}, slabgo.CacheOptions{ObjLen: 8}, slabgo.ErrInvalidFile},
+		{"objlen", Record{}, slabgo.CacheOptions{ObjLen: 16}, slabgo.ErrInvalidFile},
+	} {
+		if _, err := slabgo.OpenPersistentCache(path, c.obj, c.opts); !errors.Is(err, c.err) {
+			t.Errorf("OpenPersistentCache() %s - expected [%v], actual [%v]", c.name, c.err, err)
+		}
+	}
+
+	// corrupt header
+	data, _ := os.ReadFile(path)
+	data[32]++
+	os.WriteFile(path, data, 0o644)
+	if _, err := slabgo.OpenPersistentCache(path, Record{}, slabgo.CacheOptions{ObjLen: 8}); !errors.Is(err, slabgo.ErrInvalidFile) {
+		t.Errorf("OpenPersistentCache() checksum - expected [%v], actual [%v]", slabgo.ErrInvalidFile, err)
+	}
+
+	os.WriteFile(path, []byte("not a cache"), 0o644)
+	if _, err := slabgo.OpenPersistentCache(path, Record{}, slabgo.CacheOptions{ObjLen: 8}); !errors.Is(err, slabgo.ErrInvalidFile) {
+		t.Errorf("OpenPersistentCache() magic - expected [%v], actual [%v]", slabgo.ErrInvalidFile, err)
+	}
+}
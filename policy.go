@@ -0,0 +1,95 @@
+package slabgo
+
+import (
+	"container/heap"
+)
+
+// Policy decides which partial slab objects are allocated from
+type Policy int
+
+const (
+	PolicyAddress  Policy = iota // slab at the lowest address first (default)
+	PolicyMostFull               // slab that has the most objects in use first
+	PolicyLRU                    // least recently used slab first
+)
+
+// partial slabs ordered by policy
+type slabHeap struct {
+	ss   []*slab
+	less func(a, b *slab) bool
+}
+
+func newSlabHeap(p Policy) *slabHeap {
+	switch p {
+	case PolicyMostFull:
+		return &slabHeap{less: func(a, b *slab) bool {
+			if a.inuse != b.inuse {
+				return a.inuse > b.inuse
+			}
+			return a.smem < b.smem
+		}}
+	case PolicyLRU:
+		return &slabHeap{less: func(a, b *slab) bool { return a.used < b.used }}
+	}
+	return &slabHeap{less: func(a, b *slab) bool { return a.smem < b.smem }}
+}
+
+func (h *slabHeap) Len() int {
+	return len(h.ss)
+}
+
+func (h *slabHeap) Less(i, j int) bool {
+	return h.less(h.ss[i], h.ss[j])
+}
+
+func (h *slabHeap) Swap(i, j int) {
+	h.ss[i], h.ss[j] = h.ss[j], h.ss[i]
+	h.ss[i].order = i
+	h.ss[j].order = j
+}
+
+func (h *slabHeap) Push(x interface{}) {
+	s := x.(*slab)
+	s.order = len(h.ss)
+	h.ss = append(h.ss, s)
+}
+
+func (h *slabHeap) Pop() interface{} {
+	last := len(h.ss) - 1
+	s := h.ss[last]
+	h.ss[last] = nil
+	h.ss = h.ss[:last]
+	s.order = -1
+	return s
+}
+
+// add a slab to partial list
+func (c *Cache) toPartial(s *slab) {
+	c.partial.push(s)
+	c.tick++
+	s.used = c.tick
+	heap.Push(c.order, s)
+}
+
+// remove a slab from partial list
+func (c *Cache) fromPartial(s *slab) *slab {
+	heap.Remove(c.order, s.order)
+	return c.partial.remove(s)
+}
+
+// return a partial slab to allocate from
+func (c *Cache) nextPartial() *slab {
+	return c.order.ss[0]
+}
+
+// update a partial slab after objects are allocated or freed,
+// it is moved to full list if all objects are in use.
+func (c *Cache) touch(s *slab) {
+	if s.total == s.inuse {
+		c.full.push(c.fromPartial(s))
+	} else if c.policy != PolicyAddress {
+		c.tick++
+		s.used = c.tick
+		heap.Fix(c.order, s.order)
+	}
+}
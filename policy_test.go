@@ -0,0 +1,77 @@
+package slabgo_test
+
+import (
+	"errors"
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+func TestPolicyInvalid(t *testing.T) {
+	var foo Foo
+	for _, p := range []slabgo.Policy{-1, slabgo.PolicyLRU + 1} {
+		if _, err := slabgo.NewCacheE(foo, slabgo.CacheOptions{Policy: p}); !errors.Is(err, slabgo.ErrInvalidOptions) {
+			t.Errorf("NewCacheE() policy %d - expected [%v], actual [%v]", p, slabgo.ErrInvalidOptions, err)
+		}
+	}
+}
+
+func TestPolicyOrder(t *testing.T) {
+	var foo Foo
+
+	objLen := 8
+	for _, p := range []slabgo.Policy{slabgo.PolicyAddress, slabgo.PolicyMostFull, slabgo.PolicyLRU} {
+		cache := slabgo.NewCache(foo, slabgo.CacheOptions{
+			ObjLen: objLen,
+			Grower: func(s *slabgo.CacheStats) int { return 1 },
+			Policy: p,
+		})
+
+		// three full slabs
+		groups := make([][]*Foo, 3)
+		for i := range groups {
+			for j := 0; j < objLen; j++ {
+				groups[i] = append(groups[i], cache.Alloc().(*Foo))
+			}
+		}
+
+		// first slab is least recently used, and second slab is most full
+		for _, f := range groups[0][:3] {
+			cache.Free(f)
+		}
+		cache.Free(groups[2][0])
+		for _, f := range groups[1][:2] {
+			cache.Free(f)
+		}
+
+		var exp int
+		switch p {
+		case slabgo.PolicyAddress:
+			for i := range groups {
+				if uintptr(unsafe.Pointer(groups[i][0])) < uintptr(unsafe.Pointer(groups[exp][0])) {
+					exp = i
+				}
+			}
+		case slabgo.PolicyMostFull:
+			exp = 2
+		case slabgo.PolicyLRU:
+			exp = 0
+		}
+
+		f := cache.Alloc().(*Foo)
+		found := false
+		for _, g := range groups[exp] {
+			found = found || g == f
+		}
+		if !found {
+			t.Errorf("Alloc() policy %d - expected an object of slab %d", p, exp)
+		}
+
+		var stats slabgo.CacheStats
+		if cache.ReadStats(&stats); stats.InuseObjs != objLen*3-5 {
+			t.Errorf("Alloc() policy %d - inuse objs: expected [%d], actual [%d]", p, objLen*3-5, stats.InuseObjs)
+		}
+		cache.Destroy()
+	}
+}
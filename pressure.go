@@ -0,0 +1,143 @@
+package slabgo
+
+import (
+	"math"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// current pressure level in bits of float64
+var pressure atomic.Uint64
+
+// Return the current memory pressure level measured by PressureMonitor.
+// 0 means no pressure, and 1 means the live heap reaches the memory limit.
+func Pressure() float64 {
+	return math.Float64frombits(pressure.Load())
+}
+
+// Options for creating a PressureMonitor
+type PressureOptions struct {
+	Interval  time.Duration               // interval of measurement (default 1s)
+	Threshold float64                     // ratio of live heap to memory limit where pressure starts (default 0.8)
+	Read      func() (live, limit uint64) // return bytes of live heap and memory limit (default runtime/metrics)
+}
+
+// PressureMonitor measures memory pressure from the live heap and GOMEMLIMIT,
+// and reaps empty slabs of named caches while there is pressure.
+//
+// NOTE: Caches that are not concurrent are not reaped, because the monitor runs on other goroutine.
+type PressureMonitor struct {
+	interval  time.Duration
+	threshold float64
+	read      func() (live, limit uint64)
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{} // closed when measurement on background is finished
+}
+
+// Create a PressureMonitor with options
+func NewPressureMonitor(opts PressureOptions) *PressureMonitor {
+	m := &PressureMonitor{
+		interval:  opts.Interval,
+		threshold: opts.Threshold,
+		read:      opts.Read,
+	}
+	if m.interval <= 0 {
+		m.interval = time.Second
+	}
+	if m.threshold <= 0 || m.threshold >= 1 {
+		m.threshold = 0.8
+	}
+	if m.read == nil {
+		m.read = readMemStats
+	}
+	return m
+}
+
+// Start measurement on background
+func (m *PressureMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		return
+	}
+
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Check()
+			case <-stop:
+				return
+			}
+		}
+	}(m.stop, m.done)
+}
+
+// Stop measurement, and reset pressure level
+func (m *PressureMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		// a measurement in progress must not overwrite the reset level
+		<-m.done
+		m.stop, m.done = nil, nil
+	}
+	pressure.Store(0)
+}
+
+// Measure pressure level once, and reap empty slabs if there is pressure.
+// return pressure level.
+func (m *PressureMonitor) Check() float64 {
+	live, limit := m.read()
+	level := 0.0
+	if limit > 0 && limit < math.MaxInt64 {
+		level = (float64(live)/float64(limit) - m.threshold) / (1 - m.threshold)
+		level = math.Max(0, math.Min(1, level))
+	}
+	pressure.Store(math.Float64bits(level))
+
+	if level > 0 {
+		for _, c := range Caches() {
+			if c.concurrent {
+				c.relieve()
+			}
+		}
+	}
+	return level
+}
+
+// return objects held by magazines, and ask reaper to free empty slabs
+func (c *Cache) relieve() int {
+	c.lockMags()
+	defer c.unlockMags()
+	c.lock()
+	defer c.unlock()
+
+	c.drain()
+	return c.reap()
+}
+
+func readMemStats() (live, limit uint64) {
+	samples := []metrics.Sample{
+		{Name: "/gc/heap/live:bytes"},
+		{Name: "/gc/gomemlimit:bytes"},
+	}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		live = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		limit = samples[1].Value.Uint64()
+	}
+	return
+}
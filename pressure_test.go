@@ -0,0 +1,144 @@
+package slabgo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/k-sone/slabgo"
+)
+
+type fakeMem struct {
+	live, limit uint64
+}
+
+func (m *fakeMem) Read() (uint64, uint64) {
+	return m.live, m.limit
+}
+
+func checkPressure(t *testing.T, name string, act, exp float64) {
+	if act < exp-1e-9 || act > exp+1e-9 {
+		t.Errorf("%s - pressure: expected [%f], actual [%f]", name, exp, act)
+	}
+}
+
+func TestPressureLevel(t *testing.T) {
+	mem := &fakeMem{limit: 1000}
+	m := slabgo.NewPressureMonitor(slabgo.PressureOptions{Threshold: 0.6, Read: mem.Read})
+	defer m.Stop()
+
+	for _, c := range []struct {
+		live, limit uint64
+		level       float64
+	}{
+		{100, 1000, 0},
+		{600, 1000, 0},
+		{700, 1000, 0.25},
+		{900, 1000, 0.75},
+		{1200, 1000, 1},
+		{1200, 0, 0},
+	} {
+		mem.live, mem.limit = c.live, c.limit
+		checkPressure(t, "Check()", m.Check(), c.level)
+		checkPressure(t, "Pressure()", slabgo.Pressure(), c.level)
+
+		var stats slabgo.CacheStats
+		slabgo.NewCacheSimple(Foo{}).ReadStats(&stats)
+		checkPressure(t, "ReadStats()", stats.Pressure, c.level)
+	}
+
+	m.Stop()
+	checkPressure(t, "Stop()", slabgo.Pressure(), 0)
+}
+
+func TestPressureReap(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	objLen := 8
+	newCache := func(name string, opts slabgo.CacheOptions) *slabgo.Cache {
+		opts.Name = name
+		opts.ObjLen = objLen
+		opts.MagazineSize = -1
+		opts.Grower = func(s *slabgo.CacheStats) int { return 4 }
+		cache := slabgo.NewCache(foo, opts)
+		cache.Free(cache.Alloc())
+		return cache
+	}
+
+	var seen float64
+	def := newCache("pressure_default", slabgo.CacheOptions{Concurrent: true})
+	defer def.Destroy()
+	custom := newCache("pressure_custom", slabgo.CacheOptions{
+		Concurrent: true,
+		Reaper: func(s *slabgo.CacheStats) int {
+			seen = s.Pressure
+			return 0
+		},
+	})
+	defer custom.Destroy()
+	single := newCache("pressure_single", slabgo.CacheOptions{})
+	defer single.Destroy()
+
+	mem := &fakeMem{live: 50, limit: 100}
+	m := slabgo.NewPressureMonitor(slabgo.PressureOptions{Read: mem.Read})
+	defer m.Stop()
+
+	// no pressure
+	m.Check()
+	for _, c := range []*slabgo.Cache{def, custom, single} {
+		if c.ReadStats(&stats); stats.TotalSlabs != 4 {
+			t.Errorf("Check() %s - total slabs: expected [4], actual [%d]", c.Name(), stats.TotalSlabs)
+		}
+	}
+
+	// a half of empty slabs are reaped
+	mem.live = 90
+	m.Check()
+	if def.ReadStats(&stats); stats.TotalSlabs != 2 || stats.Reaps != 1 {
+		t.Errorf("Check() - default reaper: unexpected stats %+v", stats)
+	}
+	if custom.ReadStats(&stats); stats.TotalSlabs != 4 {
+		t.Errorf("Check() - custom reaper: unexpected stats %+v", stats)
+	}
+	checkPressure(t, "Reaper", seen, 0.5)
+	if single.ReadStats(&stats); stats.TotalSlabs != 4 {
+		t.Errorf("Check() - not concurrent: unexpected stats %+v", stats)
+	}
+
+	// all empty slabs are reaped
+	mem.live = 100
+	m.Check()
+	if def.ReadStats(&stats); stats.TotalSlabs != 0 {
+		t.Errorf("Check() - default reaper: unexpected stats %+v", stats)
+	}
+}
+
+func TestPressureBackground(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{Name: "pressure_background", ObjLen: 8, Concurrent: true})
+	defer cache.Destroy()
+	cache.Free(cache.Alloc())
+
+	mem := &fakeMem{live: 100, limit: 100}
+	m := slabgo.NewPressureMonitor(slabgo.PressureOptions{Interval: time.Millisecond, Read: mem.Read})
+	m.Start()
+	defer m.Stop()
+
+	for i := 0; i < 1000; i++ {
+		if cache.ReadStats(&stats); stats.TotalSlabs == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("monitor - slab is not reaped")
+}
+
+func TestPressureRuntime(t *testing.T) {
+	m := slabgo.NewPressureMonitor(slabgo.PressureOptions{})
+	defer m.Stop()
+	if level := m.Check(); level < 0 || level > 1 {
+		t.Errorf("Check() - invalid level [%f]", level)
+	}
+}
@@ -0,0 +1,65 @@
+package slabgo
+
+// Call `fn` for each object in use, until `fn` returns false.
+//
+// `fn` may free any object including the visited one, and may allocate objects.
+// An object allocated during Range may or may not be visited.
+// On a non-concurrent cache, an object freed before visited is not visited.
+// On a concurrent cache, objects in use are collected slab by slab under lock, and `fn` is called
+// without lock, so an object freed after its slab is collected (by `fn` or other goroutines)
+// may still be visited.
+func (c *Cache) Range(fn func(objp interface{}) bool) {
+	var objs []interface{}
+	for _, s := range c.inuseSlabs() {
+		if !c.concurrent {
+			for i := 0; i < s.total; i++ {
+				if s.bufctl[i>>3]&(1<<uint(i&0x7)) != 0 && !fn(s.chunk[i]) {
+					return
+				}
+			}
+			continue
+		}
+
+		objs = c.collect(s, objs[:0])
+		for i, o := range objs {
+			objs[i] = nil
+			if !fn(o) {
+				return
+			}
+		}
+	}
+}
+
+// return slabs that have objects in use
+func (c *Cache) inuseSlabs() []*slab {
+	c.lockMags()
+	defer c.unlockMags()
+	c.lock()
+	defer c.unlock()
+
+	c.drain()
+	ss := make([]*slab, 0, c.full.len+c.partial.len)
+	for _, l := range []*slabList{&c.full, &c.partial} {
+		for s := l.head; s != nil; s = s.next {
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
+
+// append objects in use within `s` to `objs`
+func (c *Cache) collect(s *slab, objs []interface{}) []interface{} {
+	c.lockMags()
+	defer c.unlockMags()
+	c.lock()
+	defer c.unlock()
+
+	// objects held by magazines are not in use
+	c.drain()
+	for i := 0; i < s.total; i++ {
+		if s.bufctl[i>>3]&(1<<uint(i&0x7)) != 0 {
+			objs = append(objs, s.chunk[i])
+		}
+	}
+	return objs
+}
@@ -0,0 +1,97 @@
+package slabgo_test
+
+import (
+	"testing"
+
+	"github.com/k-sone/slabgo"
+)
+
+func rangeCount(cache *slabgo.TypedCache[Foo]) (n int, sum int64) {
+	cache.Range(func(f *Foo) bool {
+		n++
+		sum += f.count
+		return true
+	})
+	return
+}
+
+func TestRange(t *testing.T) {
+	objLen := 8
+	for _, opts := range []slabgo.CacheOptions{
+		{ObjLen: objLen},
+		{ObjLen: objLen, Concurrent: true},
+		{ObjLen: objLen, Concurrent: true, MagazineSize: -1},
+	} {
+		opts.Grower = func(s *slabgo.CacheStats) int { return 1 }
+		cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{CacheOptions: opts})
+		if n, _ := rangeCount(cache); n != 0 {
+			t.Errorf("Range() - empty: expected [0], actual [%d]", n)
+		}
+
+		foos := make([]*Foo, objLen*3)
+		cache.AllocN(foos)
+		for i, f := range foos {
+			f.count = int64(i + 1)
+		}
+		// odd objects and a whole slab are freed
+		for i, f := range foos {
+			if i%2 == 1 || i >= objLen*2 {
+				cache.Free(f)
+			}
+		}
+
+		n, sum := rangeCount(cache)
+		if n != objLen || sum != int64(objLen*objLen) {
+			t.Errorf("Range() - count, sum: expected [%d, %d], actual [%d, %d]", objLen, objLen*objLen, n, sum)
+		}
+
+		// stop iteration
+		n = 0
+		cache.Range(func(f *Foo) bool {
+			n++
+			return n < 3
+		})
+		if n != 3 {
+			t.Errorf("Range() - stop: expected [3], actual [%d]", n)
+		}
+
+		// free visited objects
+		cache.Range(func(f *Foo) bool {
+			cache.Free(f)
+			return true
+		})
+		if n, _ := rangeCount(cache); n != 0 {
+			t.Errorf("Range() - free: expected [0], actual [%d]", n)
+		}
+		checkStats(t, "Range()", cache.Cache(), &slabgo.CacheStats{
+			TotalSlabs: 3,
+			TotalObjs:  objLen * 3,
+			Allocs:     uint64(objLen * 3),
+			Frees:      uint64(objLen * 3),
+		})
+	}
+}
+
+func TestRangeFreeOthers(t *testing.T) {
+	var foo Foo
+	objLen := 8
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: objLen})
+
+	foos := make([]interface{}, objLen*2)
+	cache.AllocN(foos)
+
+	// objects freed before visited are skipped
+	n := 0
+	cache.Range(func(objp interface{}) bool {
+		n++
+		for _, f := range foos {
+			if f != objp {
+				cache.Free(f)
+			}
+		}
+		return true
+	})
+	if n != 1 {
+		t.Errorf("Range() - expected [1], actual [%d]", n)
+	}
+}
@@ -0,0 +1,56 @@
+package slabgo
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+)
+
+// caches that have a name
+var registry struct {
+	mu     sync.Mutex
+	caches []*Cache
+}
+
+func register(c *Cache) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.caches = append(registry.caches, c)
+}
+
+func unregister(c *Cache) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for i, rc := range registry.caches {
+		if rc == c {
+			copy(registry.caches[i:], registry.caches[i+1:])
+			registry.caches[len(registry.caches)-1] = nil
+			registry.caches = registry.caches[:len(registry.caches)-1]
+			return
+		}
+	}
+}
+
+// Return caches that have a name and are not destroyed, in order of creation
+func Caches() []*Cache {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	return append([]*Cache(nil), registry.caches...)
+}
+
+// Write statistics of caches that have a name to `w`, like /proc/slabinfo.
+//
+// NOTE: Statistics are read from the calling goroutine, so caches used by other goroutines
+// must be created with `CacheOptions.Concurrent`.
+func WriteSlabinfo(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(tw, "# name\t<type>\t<active_objs>\t<num_objs>\t<objsize>\t<objperslab>\t: slabdata\t<active_slabs>\t<num_slabs>")
+	for _, c := range Caches() {
+		var s CacheStats
+		c.ReadStats(&s)
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t: slabdata\t%d\t%d\n",
+			c.name, c.objType, s.InuseObjs, s.TotalObjs, c.objType.Size(), c.objLen, s.InuseSlabs, s.TotalSlabs)
+	}
+	return tw.Flush()
+}
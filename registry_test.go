@@ -0,0 +1,76 @@
+package slabgo_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+func registered(c *slabgo.Cache) bool {
+	for _, rc := range slabgo.Caches() {
+		if rc == c {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegistry(t *testing.T) {
+	var foo Foo
+	named := slabgo.NewCache(foo, slabgo.CacheOptions{Name: "registry_foo"})
+	unnamed := slabgo.NewCacheSimple(foo)
+	typed := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Sample]{
+		CacheOptions: slabgo.CacheOptions{Name: "registry_sample"},
+	})
+
+	if n := named.Name(); n != "registry_foo" {
+		t.Errorf("Name() - expected [registry_foo], actual [%s]", n)
+	}
+	if !registered(named) || !registered(typed.Cache()) {
+		t.Error("Caches() - named cache not found")
+	}
+	if registered(unnamed) {
+		t.Error("Caches() - unnamed cache found")
+	}
+
+	named.Destroy()
+	typed.Destroy()
+	if registered(named) || registered(typed.Cache()) {
+		t.Error("Caches() - destroyed cache found")
+	}
+}
+
+func TestWriteSlabinfo(t *testing.T) {
+	var foo Foo
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{Name: "slabinfo_foo", ObjLen: 8})
+	defer cache.Destroy()
+	for i := 0; i < 10; i++ {
+		cache.Alloc()
+	}
+	empty := slabgo.NewCache(foo, slabgo.CacheOptions{Name: "slabinfo_empty"})
+	defer empty.Destroy()
+
+	var buf bytes.Buffer
+	if err := slabgo.WriteSlabinfo(&buf); err != nil {
+		t.Fatalf("WriteSlabinfo() - failed: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "# name ") {
+		t.Errorf("WriteSlabinfo() - header not found in\n%s", out)
+	}
+	size := unsafe.Sizeof(foo)
+	for _, re := range []string{
+		fmt.Sprintf(`(?m)^slabinfo_foo +slabgo_test\.Foo +10 +16 +%d +8 +: slabdata +2 +2$`, size),
+		fmt.Sprintf(`(?m)^slabinfo_empty +slabgo_test\.Foo +0 +0 +%d +256 +: slabdata +0 +0$`, size),
+	} {
+		if !regexp.MustCompile(re).MatchString(out) {
+			t.Errorf("WriteSlabinfo() - %q not matched in\n%s", re, out)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package slabgo_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+func TestResetZeroOnAlloc(t *testing.T) {
+	var foo Foo
+	var sample Sample
+
+	objLen := 8
+	for _, opts := range []slabgo.CacheOptions{
+		{ObjLen: objLen, ZeroOnAlloc: true},
+		{ObjLen: objLen, ZeroOnAlloc: true, Concurrent: true},
+		{ObjLen: objLen, ZeroOnAlloc: true, Debug: true},
+	} {
+		fc := slabgo.NewCache(foo, opts)
+		sc := slabgo.NewCache(sample, opts)
+
+		// dirty all objects, and reuse them by each alloc method
+		for n := 0; n < 4; n++ {
+			foos := make([]interface{}, objLen*2)
+			samples := make([]interface{}, objLen*2)
+			switch n {
+			case 0, 1:
+				for i := range foos {
+					foos[i] = fc.Alloc()
+					samples[i] = sc.Alloc()
+				}
+			case 2:
+				fc.AllocN(foos)
+				sc.AllocN(samples)
+			case 3:
+				for i := range foos {
+					_, foos[i] = fc.AllocHandle()
+					_, samples[i] = sc.AllocHandle()
+				}
+			}
+
+			for i := range foos {
+				f, s := foos[i].(*Foo), samples[i].(*Sample)
+				if f.name != "" || f.count != 0 || f.next != nil || s.id != 0 || s.value != 0 {
+					t.Fatalf("Alloc() %d - stale data %v %v", n, *f, *s)
+				}
+				f.name, f.count, f.next = "foo", 1, f
+				s.id, s.value = 1, 1.0
+			}
+			for i := range foos {
+				fc.FreePtr(unsafe.Pointer(foos[i].(*Foo)))
+				sc.FreePtr(unsafe.Pointer(samples[i].(*Sample)))
+			}
+		}
+	}
+}
+
+func TestResetHook(t *testing.T) {
+	var foo Foo
+	var resets int
+	reset := func(objp interface{}) {
+		f := objp.(*Foo)
+		f.name = "reset"
+		f.next = nil
+		resets++
+	}
+
+	// on alloc, after constructor and zeroing
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{
+		ObjLen:      8,
+		ZeroOnAlloc: true,
+		Constructor: func(objp interface{}) { objp.(*Foo).count = 1 },
+		Reset:       reset,
+	})
+	f := cache.Alloc().(*Foo)
+	if f.name != "reset" || f.count != 0 || resets != 1 {
+		t.Errorf("Alloc() - not reset %v", *f)
+	}
+	f.name = "dirty"
+	cache.Free(f)
+	if f.name != "dirty" || resets != 1 {
+		t.Errorf("Free() - reset on free")
+	}
+
+	// on free
+	resets = 0
+	for _, concurrent := range []bool{false, true} {
+		cache = slabgo.NewCache(foo, slabgo.CacheOptions{
+			Concurrent:  concurrent,
+			Reset:       reset,
+			ResetOnFree: true,
+		})
+		f = cache.Alloc().(*Foo)
+		f.name, f.next = "dirty", f
+		if f.name != "dirty" {
+			t.Error("Alloc() - reset on alloc")
+		}
+		cache.Free(f)
+		if f.name != "reset" || f.next != nil {
+			t.Errorf("Free() - not reset %v", *f)
+		}
+		// invalid free is not reset
+		f.name = "freed"
+		cache.Free(f)
+		if f.name != "freed" {
+			t.Errorf("Free() - double free is reset")
+		}
+	}
+	if resets != 2 {
+		t.Errorf("Free() - resets: expected [2], actual [%d]", resets)
+	}
+}
@@ -0,0 +1,55 @@
+package slabgo
+
+import (
+	"time"
+)
+
+// Destroy empty slabs that have been idle for `CacheOptions.IdleAge` or more.
+// Objects held by magazines are returned to slabs before that.
+// return number of slabs destroyed.
+func (c *Cache) Shrink() int {
+	c.lockMags()
+	defer c.unlockMags()
+	c.lock()
+	defer c.unlock()
+
+	c.drain()
+	if c.file != nil {
+		// slabs of file are kept
+		return 0
+	}
+
+	now := c.clock()
+	num := 0
+	for s := c.empty.head; s != nil; {
+		next := s.next
+		if now.Sub(s.idle) >= c.idleAge {
+			c.destroySlab(s, nil)
+			num++
+		}
+		s = next
+	}
+	if num > 0 {
+		c.dropped += uint64(num)
+	}
+	return num
+}
+
+func (c *Cache) toEmpty(s *slab) {
+	s.idle = c.clock()
+	c.empty.push(s)
+}
+
+// run Shrink periodically until `stop` is closed
+func (c *Cache) shrinker(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.Shrink()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package slabgo_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/k-sone/slabgo"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestShrinkIdle(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	objLen := 8
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	var dnum int
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{
+		ObjLen:     objLen,
+		Grower:     func(s *slabgo.CacheStats) int { return 1 },
+		IdleAge:    time.Minute,
+		Clock:      clock.Now,
+		Destructor: counter(&dnum),
+	})
+
+	var foos []interface{}
+	for i := 0; i < objLen*3; i++ {
+		foos = append(foos, cache.Alloc())
+	}
+
+	// 1st slab becomes empty
+	for _, f := range foos[:objLen] {
+		cache.Free(f)
+	}
+	clock.Advance(30 * time.Second)
+
+	// 2nd slab becomes empty
+	for _, f := range foos[objLen : objLen*2] {
+		cache.Free(f)
+	}
+	if n := cache.Shrink(); n != 0 {
+		t.Errorf("Shrink() - expected [0], actual [%d]", n)
+	}
+
+	clock.Advance(30 * time.Second)
+	if n := cache.Shrink(); n != 1 {
+		t.Errorf("Shrink() - expected [1], actual [%d]", n)
+	}
+
+	name := "Shrink() 1st"
+	stats.TotalSlabs = 2
+	stats.InuseSlabs = 1
+	stats.TotalObjs = objLen * 2
+	stats.InuseObjs = objLen
+	stats.Allocs = uint64(objLen * 3)
+	stats.Frees = uint64(objLen * 2)
+	checkStats(t, name, cache, &stats)
+	checkDestruct(t, name, dnum, objLen)
+
+	clock.Advance(30 * time.Second)
+	if n := cache.Shrink(); n != 1 {
+		t.Errorf("Shrink() - expected [1], actual [%d]", n)
+	}
+
+	name = "Shrink() 2nd"
+	stats.TotalSlabs = 1
+	stats.TotalObjs = objLen
+	checkStats(t, name, cache, &stats)
+	checkDestruct(t, name, dnum, objLen*2)
+
+	// reused slab is not idle
+	cache.Free(foos[objLen*2])
+	cache.Alloc()
+	clock.Advance(time.Hour)
+	if n := cache.Shrink(); n != 0 {
+		t.Errorf("Shrink() - expected [0], actual [%d]", n)
+	}
+}
+
+func TestShrinkMagazine(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, Concurrent: true})
+	cache.Free(cache.Alloc())
+
+	// all objects are held by magazine
+	if n := cache.Shrink(); n == 0 {
+		t.Error("Shrink() - no slab destroyed")
+	}
+	cache.ReadStats(&stats)
+	if stats.TotalSlabs != 0 || stats.InuseObjs != 0 || stats.Allocs != 1 || stats.Frees != 1 {
+		t.Errorf("Shrink() - unexpected stats %+v", stats)
+	}
+}
+
+func TestShrinkBackground(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	if _, err := slabgo.NewCacheE(foo, slabgo.CacheOptions{ShrinkInterval: time.Millisecond}); !errors.Is(err, slabgo.ErrInvalidOptions) {
+		t.Errorf("NewCacheE() - expected [%v], actual [%v]", slabgo.ErrInvalidOptions, err)
+	}
+
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{
+		ObjLen:         8,
+		Concurrent:     true,
+		MagazineSize:   -1,
+		ShrinkInterval: time.Millisecond,
+	})
+	defer cache.Destroy()
+
+	cache.Free(cache.Alloc())
+	for i := 0; i < 1000; i++ {
+		if cache.ReadStats(&stats); stats.TotalSlabs == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("shrinker - slab is not destroyed")
+}
@@ -1,11 +1,38 @@
 package slabgo
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"reflect"
-	"sort"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
+// Errors returned by `Cache.FreePtrErr`
+var (
+	ErrDoubleFree = errors.New("slabgo: object is already freed")
+	ErrNotOwned   = errors.New("slabgo: object is not owned by cache")
+	ErrMisaligned = errors.New("slabgo: pointer is not at the start of object")
+)
+
+// Errors returned by `NewCacheE` and `Cache.TryAlloc`
+var (
+	ErrInvalidObject  = errors.New("slabgo: invalid object")
+	ErrZeroSize       = errors.New("slabgo: object has zero size")
+	ErrInvalidOptions = errors.New("slabgo: invalid options")
+	ErrLimit          = errors.New("slabgo: limit of cache reached")
+	ErrGrowerDeclined = errors.New("slabgo: grower declined to add slab")
+	ErrDestroyed      = errors.New("slabgo: cache is destroyed")
+	ErrNotSupported   = errors.New("slabgo: not supported on this platform")
+)
+
+// ErrInUse is returned by `Cache.Destroy` and `Cache.DestroyStrict` if objects are still in use
+var ErrInUse = errors.New("slabgo: objects are still in use")
+
 var ntzMatrix [256]byte // The number of training zero
 
 func buildNtzMatrix() {
@@ -25,9 +52,13 @@ func buildNtzMatrix() {
 type Constructor func(objp interface{})
 
 // Destructor is called when a slab is destroyed.
-// `objp` is a pointer of each object.
+// `objp` is a pointer of each object that is constructed or has been allocated.
 type Destructor func(objp interface{})
 
+// DestructorE is a Destructor that is told whether an object is still in use,
+// and returns an error which is reported by `Cache.Destroy`.
+type DestructorE func(objp interface{}, inuse bool) error
+
 // Grower is called when there is not free slab.
 // return an increment number of slabs.
 type Grower func(s *CacheStats) int
@@ -48,72 +79,290 @@ var DefaultGrower Grower = func(s *CacheStats) int {
 }
 
 // Default implementation of reaper.
-// slab is never freed.
+// slab is never freed unless there is memory pressure measured by PressureMonitor,
+// then empty slabs are freed in proportion to the pressure.
 var DefaultReaper Reaper = func(s *CacheStats) int {
-	return 0
+	return int(math.Ceil(float64(s.TotalSlabs-s.InuseSlabs) * s.Pressure))
 }
 
 // Options for creating a Cache
 type CacheOptions struct {
-	ObjLen      int // length of object array within a slab, this is must be multiple of 8
+	Name        string // name of cache, a named cache is listed by `WriteSlabinfo` until destroyed
+	ObjLen      int    // length of object array within a slab, this is must be multiple of 8
 	Grower      Grower
 	Reaper      Reaper
 	Constructor Constructor
 	Destructor  Destructor
+	DestructorE DestructorE // used instead of Destructor if set
+
+	// Concurrent makes a cache goroutine-safe.
+	// Alloc and Free go through per-shard magazines in front of the shared slab lists.
+	Concurrent bool
+	// MagazineSize is the number of objects held by each magazine (default 64).
+	// Negative value disables magazines, then a concurrent cache uses only a mutex.
+	MagazineSize int
+
+	// PanicOnInvalidFree makes Free and FreePtr panic instead of returning an error,
+	// this is intended for debug builds. Magazines of a concurrent cache are disabled.
+	PanicOnInvalidFree bool
+
+	// Debug enables red zones around objects and poisoning of freed objects.
+	// A freed object is filled with a poison pattern (or zero if it has pointers),
+	// and verified on the next allocation to detect writes after free.
+	// Poisoning is disabled if Constructor is set, because it clobbers constructed objects.
+	// Magazines of a concurrent cache are also disabled.
+	Debug bool
+	// DebugReport is called with a *CorruptionError when corruption is detected on debug mode.
+	// If nil, it panics.
+	DebugReport func(err error)
+
+	// Track records the caller stack of each allocation for `Cache.Leaks`.
+	// Magazines of a concurrent cache are disabled.
+	Track bool
+
+	// MaxObjs and MaxBytes limit number and bytes of objects in use (0 is unlimited).
+	// Objects held by magazines are counted as in use, and taken by other magazines when slabs run dry.
+	MaxObjs  int
+	MaxBytes uint64
+
+	// IdleAge is the age of an empty slab to be destroyed by `Cache.Shrink`.
+	IdleAge time.Duration
+	// ShrinkInterval runs `Cache.Shrink` periodically on background until the cache is destroyed.
+	// This requires Concurrent.
+	ShrinkInterval time.Duration
+	// Clock returns the current time for aging of empty slabs (default time.Now).
+	Clock func() time.Time
+
+	// ZeroOnAlloc clears an object every time before it is returned by Alloc,
+	// so that no data remains from the previous use. This also clears constructed fields.
+	ZeroOnAlloc bool
+	// Reset is called with an object every time before it is returned by Alloc (after ZeroOnAlloc),
+	// or when it is freed if ResetOnFree is set. Unlike Constructor, it is called on every reuse.
+	// Reset must not call methods of the cache.
+	Reset func(objp interface{})
+	// ResetOnFree calls Reset on Free instead of Alloc.
+	ResetOnFree bool
+
+	// OnAlloc is called with an object every time before it is returned by Alloc (after Reset),
+	// and OnFree is called with an object every time it is freed (before Reset).
+	// A free that fails is not notified.
+	// They must not call methods of the cache.
+	OnAlloc func(objp interface{})
+	OnFree  func(objp interface{})
+
+	// Policy decides which partial slab objects are allocated from (default PolicyAddress).
+	// PolicyMostFull packs objects into fewer slabs, so that more slabs become empty for reaper.
+	Policy Policy
+
+	// OffHeap allocates object arrays from anonymous memory mapped outside of Go heap,
+	// so that garbage collector never scans them. The object type must not have pointers.
+	// Objects must not be used after their slab is destroyed, or it crashes.
+	// Return ErrNotSupported on platforms without mmap.
+	OffHeap bool
+
+	// Codec encodes objects of `Cache.Snapshot` (default GobCodec).
+	Codec Codec
 }
 
 // Cache statistics
 type CacheStats struct {
-	TotalSlabs     int    // number of slab
-	InuseSlabs     int    // number of slab in use
-	TotalObjs      int    // number of object
-	InuseObjs      int    // number of object in use
-	Allocs         uint64 // number of allocs
-	Frees          uint64 // number of frees
-	CacheSize      uint64 // bytes of cache size
-	CacheSizeInuse uint64 // bytes of cache size in use
+	TotalSlabs     int     // number of slab
+	InuseSlabs     int     // number of slab in use
+	TotalObjs      int     // number of object
+	InuseObjs      int     // number of object in use
+	Allocs         uint64  // number of allocs
+	Frees          uint64  // number of frees
+	CacheSize      uint64  // bytes of cache size
+	CacheSizeInuse uint64  // bytes of cache size in use
+	Grows          uint64  // number of times slabs are added by grower
+	Reaps          uint64  // number of times slabs are removed by reaper
+	Pressure       float64 // memory pressure level from 0 to 1, see `Pressure`
+	PeakInuseObjs  int     // maximum number of object in use (including objects held by magazines)
+	PeakTotalSlabs int     // maximum number of slab
+	SlabsCreated   uint64  // number of slabs created
+	SlabsDestroyed uint64  // number of slabs destroyed
+	FailedAllocs   uint64  // number of allocs that returned no object
+	PartialSlabs   int     // number of slab that has both used and free objects
+	Fragmentation  float64 // ratio of free objects within slabs in use, from 0 to 1
 }
 
 // Storage for a specific type of object
 type Cache struct {
-	full      slabs // all objects within a slab marked as used
-	partial   slabs // slab consists of both used and free objects
-	empty     slabs // all objects within a slab marked as free
+	full      slabList  // all objects within a slab marked as used
+	partial   slabList  // slab consists of both used and free objects
+	empty     slabList  // all objects within a slab marked as free, recently emptied first
+	addrs     slabIndex // all slabs by address
+	name      string
 	objType   reflect.Type
 	objLen    int
 	inuseObjs int
-	allocs    uint64
-	frees     uint64
+	allocs    uint64 // number of allocs, except ones through magazines
+	frees     uint64 // number of frees, except ones through magazines
+	grows     uint64
+	reaps     uint64
+	created   uint64 // number of slabs created
+	dropped   uint64 // number of slabs destroyed
+	failed    uint64 // number of failed allocs, updated atomically
+	peakObjs  int    // high-water mark of inuseObjs
+	peakSlabs int    // high-water mark of slabs
 	grower    Grower
 	reaper    Reaper
 	ctor      Constructor
-	dtor      Destructor
+	dtor      DestructorE
+	offHeap   bool      // object arrays are mapped outside of Go heap
+	file      *slabFile // file of slabs (persistent mode only)
+	codec     Codec
+
+	panicFree bool
+
+	debug       bool
+	poison      poisonMode
+	debugReport func(err error)
+	corruptions []error // reported after releasing lock
+
+	track bool
+
+	destroyed bool
+
+	maxObjs  int // limit of objects in use
+	maxSlabs int // limit of slabs
+	waiters  int32
+	waitMu   sync.Mutex
+	wake     chan struct{} // closed when an object is freed while there are waiters
+
+	idleAge time.Duration
+	clock   func() time.Time
+	stop    chan struct{} // closed when destroyed, to stop shrinker
+
+	policy Policy
+	order  *slabHeap // partial slabs ordered by policy
+	tick   uint64    // clock of PolicyLRU
+
+	zero        bool // clear objects on alloc
+	zeroPtrs    bool // object has pointers, it must be cleared with write barriers
+	reset       func(objp interface{})
+	resetOnFree bool
+	onAlloc     func(objp interface{})
+	onFree      func(objp interface{})
+	prepares    bool // objects must be prepared on alloc
+	freeHooks   bool // hooks are called on free
+
+	slotBits uint     // bits of slot index within a handle
+	maxID    int      // limit of slab ids
+	ids      []slabID // slabs by id
+	freeIDs  []uint32 // ids released by destroyed slabs
+
+	concurrent bool
+	mu         sync.Mutex    // protects slab lists and counters on concurrent mode
+	mags       []magazine    // per-shard magazines (concurrent mode only)
+	magSize    int           // capacity of a magazine
+	turn       atomic.Uint32 // magazine to wait for when all are locked
+}
+
+func (c *Cache) lock() {
+	if c.concurrent {
+		c.mu.Lock()
+	}
+}
+
+func (c *Cache) unlock() {
+	if c.concurrent {
+		c.mu.Unlock()
+	}
 }
 
 func (c *Cache) grow() int {
 	var s CacheStats
-	c.ReadStats(&s)
+	c.readStats(&s)
 	num := c.grower(&s)
 
 	if num < 0 {
 		num = 0
+	} else if rest := c.maxSlabs - s.TotalSlabs; num > rest {
+		num = rest
 	}
 	for i := 0; i < num; i++ {
-		(&c.empty).insert(newSlab(c.objType, c.objLen, c.ctor))
+		s := c.newSlab()
+		if s == nil {
+			// failed to map memory
+			num = i
+			break
+		}
+		c.addrs.add(s)
+		c.assignID(s)
+		c.toEmpty(s)
+	}
+	if num > 0 {
+		c.grows++
+		c.created += uint64(num)
+		if total := s.TotalSlabs + num; total > c.peakSlabs {
+			c.peakSlabs = total
+		}
 	}
 	return num
 }
 
+// create a slab, return nil if memory can not be mapped
+func (c *Cache) newSlab() *slab {
+	var s *slab
+	if c.file != nil {
+		s = c.file.newSlab(c.ctor)
+	} else {
+		s = newSlab(c.objType, c.objLen, c.ctor, c.debug, c.offHeap)
+	}
+	if s == nil {
+		return nil
+	}
+	c.initSlab(s)
+	return s
+}
+
+// prepare a slab for modes of cache
+func (c *Cache) initSlab(s *slab) {
+	if c.debug {
+		c.initDebug(s)
+	}
+	if c.track {
+		s.sites = make([]site, s.total)
+	}
+	if c.mags != nil {
+		s.owned = make([]atomic.Uint32, (s.total+31)>>5)
+	}
+}
+
+// remove a slab from list and index, and destroy it.
+// return `errs` appended errors of destructor.
+func (c *Cache) destroySlab(s *slab, errs []error) []error {
+	if s.list == &c.partial {
+		c.fromPartial(s)
+	} else {
+		s.list.remove(s)
+	}
+	c.addrs.remove(s)
+	c.releaseID(s)
+	return s.destroy(c.dtor, errs)
+}
+
 func (c *Cache) reap() int {
+	if c.file != nil {
+		// slabs of file are kept
+		return 0
+	}
+
 	var s CacheStats
-	c.ReadStats(&s)
+	c.readStats(&s)
 	num := c.reaper(&s)
 
-	if elen := len(c.empty); num > elen {
+	if elen := c.empty.len; num > elen {
 		num = elen
 	}
 	for i := 0; i < num; i++ {
-		(&c.empty).pop(len(c.empty) - 1).destroy(c.dtor)
+		// least recently emptied first, errors of destructor are discarded
+		c.destroySlab(c.empty.tail, nil)
+	}
+	if num > 0 {
+		c.reaps++
+		c.dropped += uint64(num)
 	}
 	return num
 }
@@ -121,25 +370,95 @@ func (c *Cache) reap() int {
 // Allocate an object from cache.
 // return a pointer of object.
 func (c *Cache) Alloc() (obj interface{}) {
-	if len(c.partial) == 0 {
-		if len(c.empty) == 0 && c.grow() == 0 {
+	if obj = c.allocObj(); obj == nil {
+		atomic.AddUint64(&c.failed, 1)
+	} else if c.prepares {
+		c.prepare(obj)
+	}
+	return
+}
+
+func (c *Cache) allocObj() (obj interface{}) {
+	if c.mags != nil {
+		return c.magAlloc()
+	} else if !c.concurrent && !c.debug {
+		return c.alloc()
+	}
+
+	c.lock()
+	obj = c.alloc()
+	errs := c.takeCorruptions()
+	c.unlock()
+
+	c.report(errs)
+	return
+}
+
+// return a slab that has unused objects, or nil
+func (c *Cache) available() *slab {
+	if c.partial.len == 0 {
+		if c.empty.len == 0 && c.grow() == 0 {
 			// there is no available slab
-			return
+			return nil
 		}
-		(&c.partial).insert((&c.empty).pop(0))
+		c.toPartial(c.empty.remove(c.empty.head))
+	}
+	return c.nextPartial()
+}
+
+func (c *Cache) alloc() (obj interface{}) {
+	if c.inuseObjs >= c.maxObjs || c.destroyed {
+		// reached the limit, or destroyed
+		return
+	}
+
+	s := c.available()
+	if s == nil {
+		return
 	}
 
-	s := c.partial[0]
+	i := s.first
 	obj = s.alloc()
-	if s.total == s.inuse {
-		(&c.full).insert((&c.partial).pop(0))
+	if c.debug {
+		c.checkAlloc(s, i)
 	}
+	if c.track {
+		// frames of the previous allocation must not remain
+		s.sites[i] = site{}
+		runtime.Callers(2, s.sites[i][:])
+	}
+	c.touch(s)
 
 	c.inuseObjs++
 	c.allocs++
+	if c.inuseObjs > c.peakObjs {
+		c.peakObjs = c.inuseObjs
+	}
 	return
 }
 
+// Allocate an object from cache.
+// return ErrLimit, ErrGrowerDeclined or ErrDestroyed if there is no available object.
+func (c *Cache) TryAlloc() (interface{}, error) {
+	if obj := c.Alloc(); obj != nil {
+		return obj, nil
+	}
+	return nil, c.allocErr()
+}
+
+// return the reason why an object can not be allocated
+func (c *Cache) allocErr() error {
+	c.lock()
+	defer c.unlock()
+
+	if c.destroyed {
+		return ErrDestroyed
+	} else if c.inuseObjs >= c.maxObjs || c.full.len+c.partial.len+c.empty.len >= c.maxSlabs {
+		return ErrLimit
+	}
+	return ErrGrowerDeclined
+}
+
 // NOTE: This function is slow. It is recommended that `Cache.FreePtr` call be used instead.
 //
 // Return an object to cache.
@@ -148,64 +467,188 @@ func (c *Cache) Free(objp interface{}) bool {
 	val := reflect.Indirect(reflect.ValueOf(objp))
 	if !val.IsValid() || !val.CanAddr() {
 		// invalid type
-		return false
+		return c.freeFailed(0, ErrNotOwned) == nil
 	}
-	return c.free(val.UnsafeAddr())
+	return c.freePtr(val.UnsafeAddr()) == nil
 }
 
 // Return an object to cache.
 // `objp` is a pointer of object.
 func (c *Cache) FreePtr(objp unsafe.Pointer) bool {
-	return c.free(uintptr(objp))
+	return c.freePtr(uintptr(objp)) == nil
 }
 
-func (c *Cache) free(ptr uintptr) bool {
-	// free from partial
-	if i := c.partial.find(ptr); i > -1 {
-		if s := c.partial[i]; s.free(ptr) {
-			if s.inuse == 0 {
-				(&c.empty).insert((&c.partial).pop(i))
-				c.reap()
-			}
-			c.inuseObjs--
-			c.frees++
-			return true
+// Return an object to cache.
+// `objp` is a pointer of object.
+// return ErrDoubleFree, ErrNotOwned or ErrMisaligned if `objp` is not an object in use.
+func (c *Cache) FreePtrErr(objp unsafe.Pointer) error {
+	return c.freePtr(uintptr(objp))
+}
+
+func (c *Cache) freePtr(ptr uintptr) (err error) {
+	if c.mags != nil {
+		err = c.magFree(ptr)
+	} else if !c.concurrent && !c.debug {
+		err = c.free(ptr)
+	} else {
+		err = c.lockedFree(ptr)
+	}
+	if err != nil {
+		return c.freeFailed(ptr, err)
+	}
+	if atomic.LoadInt32(&c.waiters) > 0 {
+		c.wakeup()
+	}
+	return nil
+}
+
+func (c *Cache) lockedFree(ptr uintptr) (err error) {
+	c.lock()
+	err = c.free(ptr)
+	errs := c.takeCorruptions()
+	c.unlock()
+
+	c.report(errs)
+	return
+}
+
+func (c *Cache) freeFailed(ptr uintptr, err error) error {
+	if err != nil && c.panicFree {
+		panic(fmt.Errorf("%w: %#x", err, ptr))
+	}
+	return err
+}
+
+func (c *Cache) free(ptr uintptr) error {
+	if c.mags != nil {
+		// objects held by magazines are also in use within slabs
+		s := c.addrs.get(ptr)
+		if s == nil {
+			return ErrNotOwned
+		}
+		i, err := s.indexOf(ptr)
+		if err != nil {
+			return err
+		} else if !s.disown(i) {
+			return ErrDoubleFree
 		}
 	}
+	return c.release(ptr, true)
+}
+
+// return an object to slab, free hooks are called if `hooks`
+func (c *Cache) release(ptr uintptr, hooks bool) error {
+	s := c.addrs.get(ptr)
+	if s == nil {
+		// not found
+		return ErrNotOwned
+	}
 
-	// free from full
-	if i := c.full.find(ptr); i > -1 {
-		if s := c.full[i]; s.free(ptr) {
-			if s.inuse == 0 {
-				(&c.empty).insert((&c.full).pop(i))
-				c.reap()
-			} else {
-				(&c.partial).insert((&c.full).pop(i))
-			}
-			c.inuseObjs--
-			c.frees++
-			return true
+	if s.list == &c.empty {
+		// all objects within empty slab are unused
+		if _, err := s.indexOf(ptr); err != nil {
+			return err
 		}
+		return ErrDoubleFree
 	}
 
-	// not found
-	return false
+	if err := c.freeObject(s, ptr, hooks); err != nil {
+		return err
+	}
+	full := s.list == &c.full
+	if s.inuse == 0 {
+		if full {
+			c.full.remove(s)
+		} else {
+			c.fromPartial(s)
+		}
+		c.toEmpty(s)
+		c.reap()
+	} else if full {
+		c.toPartial(c.full.remove(s))
+	} else {
+		c.touch(s)
+	}
+	c.inuseObjs--
+	c.frees++
+	return nil
+}
+
+// Explicitly destroy a cache.
+// return an error wrapping ErrInUse if objects are still in use, and errors of DestructorE.
+// The cache is destroyed even if an error is returned.
+func (c *Cache) Destroy() error {
+	return c.destroy(false)
 }
 
-// Explicitly destroy a cache
-func (c *Cache) Destroy() {
-	for i := len(c.full) - 1; i > -1; i-- {
-		(&c.full).pop(i).destroy(c.dtor)
+// Explicitly destroy a cache if no object is in use.
+// return an error wrapping ErrInUse without destroying if objects are still in use,
+// or errors of DestructorE.
+func (c *Cache) DestroyStrict() error {
+	return c.destroy(true)
+}
+
+func (c *Cache) destroy(strict bool) error {
+	c.lockMags()
+	defer c.unlockMags()
+	c.lock()
+	defer c.unlock()
+
+	inuse := c.inuseObjs
+	for i := range c.mags {
+		inuse -= len(c.mags[i].rounds)
+	}
+	var errs []error
+	if inuse > 0 && c.file == nil {
+		err := fmt.Errorf("%w: %d objects", ErrInUse, inuse)
+		if strict {
+			return err
+		}
+		errs = append(errs, err)
+	}
+
+	for i := range c.mags {
+		// objects held by magazines are not in use
+		for _, r := range c.mags[i].rounds {
+			c.addrs.get(r.ptr).free(r.ptr)
+		}
+		c.mags[i].clear()
 	}
-	for i := len(c.partial) - 1; i > -1; i-- {
-		(&c.partial).pop(i).destroy(c.dtor)
+	for _, l := range []*slabList{&c.full, &c.partial, &c.empty} {
+		for l.head != nil {
+			errs = c.destroySlab(l.head, errs)
+		}
 	}
-	for i := len(c.empty) - 1; i > -1; i-- {
-		(&c.empty).pop(i).destroy(c.dtor)
+	if c.file != nil {
+		// objects in use are kept in the file
+		errs = append(errs, c.file.close())
+		c.file = nil
 	}
 	c.inuseObjs = 0
 	c.allocs = 0
 	c.frees = 0
+	c.grows = 0
+	c.reaps = 0
+	c.created = 0
+	c.dropped = 0
+	atomic.StoreUint64(&c.failed, 0)
+	c.peakObjs = 0
+	c.peakSlabs = 0
+	c.destroyed = true
+	c.wakeup()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	if c.name != "" {
+		unregister(c)
+	}
+	return errors.Join(errs...)
+}
+
+// Return name of cache
+func (c *Cache) Name() string {
+	return c.name
 }
 
 // Return type of object
@@ -220,28 +663,84 @@ func (c *Cache) ObjectLen() int {
 
 // Populates `s` with cache statistics
 func (c *Cache) ReadStats(s *CacheStats) {
+	c.lockMags()
+	defer c.unlockMags()
+	c.lock()
+	defer c.unlock()
+
+	c.readStats(s)
+	if c.mags != nil {
+		// objects held by magazines are not in use
+		for i := range c.mags {
+			m := &c.mags[i]
+			s.InuseObjs -= len(m.rounds)
+			s.Allocs += m.allocs
+			s.Frees += m.frees
+		}
+		s.CacheSizeInuse = uint64(c.objType.Size()) * uint64(s.InuseObjs)
+		s.Fragmentation = c.fragmentation(s)
+	}
+}
+
+func (c *Cache) readStats(s *CacheStats) {
 	objSize := uint64(c.objType.Size())
 
-	s.TotalSlabs = len(c.full) + len(c.partial) + len(c.empty)
-	s.InuseSlabs = len(c.full) + len(c.partial)
+	s.TotalSlabs = c.full.len + c.partial.len + c.empty.len
+	s.InuseSlabs = c.full.len + c.partial.len
 	s.TotalObjs = s.TotalSlabs * c.objLen
 	s.InuseObjs = c.inuseObjs
 	s.Allocs = c.allocs
 	s.Frees = c.frees
 	s.CacheSize = objSize * uint64(s.TotalObjs)
 	s.CacheSizeInuse = objSize * uint64(s.InuseObjs)
+	s.Grows = c.grows
+	s.Reaps = c.reaps
+	s.Pressure = Pressure()
+	s.PeakInuseObjs = c.peakObjs
+	s.PeakTotalSlabs = c.peakSlabs
+	s.SlabsCreated = c.created
+	s.SlabsDestroyed = c.dropped
+	s.FailedAllocs = atomic.LoadUint64(&c.failed)
+	s.PartialSlabs = c.partial.len
+	s.Fragmentation = c.fragmentation(s)
+}
+
+// return ratio of free objects within slabs in use
+func (c *Cache) fragmentation(s *CacheStats) float64 {
+	objs := s.InuseSlabs * c.objLen
+	if objs == 0 {
+		return 0
+	}
+	return float64(objs-s.InuseObjs) / float64(objs)
 }
 
 // Create a Cache with options.
+// return nil if `obj` or `opts` is invalid.
 func NewCache(obj interface{}, opts CacheOptions) *Cache {
+	c, _ := NewCacheE(obj, opts)
+	return c
+}
+
+// Create a Cache with options.
+// return ErrInvalidObject, ErrZeroSize or ErrInvalidOptions if `obj` or `opts` is invalid.
+func NewCacheE(obj interface{}, opts CacheOptions) (*Cache, error) {
 	val := reflect.ValueOf(obj)
 	if !val.IsValid() {
-		return nil
+		return nil, ErrInvalidObject
 	}
 	objtype := val.Type()
 	objsize := objtype.Size()
 	if objsize < 1 {
-		return nil
+		return nil, ErrZeroSize
+	}
+	if opts.ObjLen < 0 || opts.MaxObjs < 0 || (opts.MaxBytes > 0 && opts.MaxBytes < uint64(objsize)) ||
+		opts.IdleAge < 0 || opts.ShrinkInterval < 0 || (opts.ShrinkInterval > 0 && !opts.Concurrent) ||
+		opts.Policy < PolicyAddress || opts.Policy > PolicyLRU || (opts.OffHeap && hasPointers(objtype)) ||
+		(opts.Codec == BinaryCodec && !binaryEncodable(objtype)) {
+		return nil, ErrInvalidOptions
+	}
+	if opts.OffHeap && !offHeapSupported {
+		return nil, ErrNotSupported
 	}
 
 	objlen := opts.ObjLen
@@ -261,14 +760,97 @@ func NewCache(obj interface{}, opts CacheOptions) *Cache {
 		reaper = DefaultReaper
 	}
 
-	return &Cache{
-		objType: objtype,
-		objLen:  objlen,
-		grower:  grower,
-		reaper:  reaper,
-		ctor:    opts.Constructor,
-		dtor:    opts.Destructor,
+	dtor := opts.DestructorE
+	if d := opts.Destructor; dtor == nil && d != nil {
+		dtor = func(objp interface{}, inuse bool) error { d(objp); return nil }
+	}
+
+	c := &Cache{
+		name:       opts.Name,
+		objType:    objtype,
+		objLen:     objlen,
+		grower:     grower,
+		reaper:     reaper,
+		ctor:       opts.Constructor,
+		dtor:       dtor,
+		offHeap:    opts.OffHeap,
+		panicFree:  opts.PanicOnInvalidFree,
+		concurrent: opts.Concurrent,
+	}
+	if opts.Debug {
+		c.debug = true
+		c.debugReport = opts.DebugReport
+		c.poison = poisonModeOf(objtype, opts.Constructor)
+	}
+	c.track = opts.Track
+	etype := objtype
+	if c.debug {
+		etype = redzoneType(objtype)
+	}
+	c.addrs = newSlabIndex(etype.Size() * uintptr(objlen))
+	c.maxObjs = maxObjs(objsize, opts.MaxObjs, opts.MaxBytes)
+	c.maxSlabs = math.MaxInt
+	if c.maxObjs < math.MaxInt {
+		c.maxSlabs = (c.maxObjs + objlen - 1) / objlen
+	}
+	c.zero = opts.ZeroOnAlloc
+	c.zeroPtrs = hasPointers(objtype)
+	c.reset = opts.Reset
+	c.resetOnFree = opts.Reset != nil && opts.ResetOnFree
+	c.onAlloc = opts.OnAlloc
+	c.onFree = opts.OnFree
+	c.prepares = c.zero || (c.reset != nil && !c.resetOnFree) || c.onAlloc != nil
+	c.freeHooks = c.resetOnFree || c.onFree != nil
+	if c.concurrent && !c.debug && !c.track && !c.panicFree && opts.MagazineSize >= 0 {
+		c.magSize = opts.MagazineSize
+		if c.magSize == 0 {
+			c.magSize = 64
+		}
+		c.mags = newMagazines(c.magSize)
+		c.addrs.shared = new(sync.Map)
+	}
+	c.idleAge = opts.IdleAge
+	c.clock = opts.Clock
+	if c.clock == nil {
+		c.clock = time.Now
 	}
+	c.policy = opts.Policy
+	c.codec = opts.Codec
+	if c.codec == nil {
+		c.codec = GobCodec
+	}
+	c.initHandles()
+	c.order = newSlabHeap(opts.Policy)
+	if opts.ShrinkInterval > 0 {
+		c.stop = make(chan struct{})
+		go c.shrinker(opts.ShrinkInterval, c.stop)
+	}
+	if c.name != "" {
+		register(c)
+	}
+	return c, nil
+}
+
+func (c *Cache) freeObject(s *slab, ptr uintptr, hooks bool) error {
+	i, err := s.free(ptr)
+	if err == nil && hooks && c.freeHooks {
+		c.freed(s.chunk[i])
+	}
+	if err == nil && c.debug {
+		c.checkFree(s, i)
+	}
+	return err
+}
+
+func maxObjs(objsize uintptr, objs int, bytes uint64) int {
+	max := math.MaxInt
+	if objs > 0 {
+		max = objs
+	}
+	if n := bytes / uint64(objsize); bytes > 0 && n < uint64(max) {
+		max = int(n)
+	}
+	return max
 }
 
 // Create a Cache simply.
@@ -285,11 +867,25 @@ type slab struct {
 	emem    uintptr // end address of object array within slab
 	bufctl  []byte  // bits of use state(0: unused, 1: inuse)
 	chunk   []interface{}
+	mem     []byte    // raw memory of object array
+	mapping []byte    // memory mapped outside of Go heap, unmapped when destroyed
+	idle    time.Time // time when all objects become unused
+	order   int       // position within slabHeap
+	list    *slabList // list that slab belongs to
+	prev    *slab
+	next    *slab
+	id      uint32          // id for handles (0 is no id)
+	gens    []uint8         // generation of each object for handles
+	used    uint64          // tick when objects are allocated or freed lately
+	sites   []site          // allocation site of each object (tracking mode only)
+	owned   []atomic.Uint32 // bits of objects owned by user (magazine mode only)
+	touched []byte          // bits of objects that are constructed or have been allocated
 }
 
 func (s *slab) alloc() (obj interface{}) {
 	obj = s.chunk[s.first]
 	s.bufctl[s.first>>3] |= (1 << uint(s.first&0x7))
+	s.touched[s.first>>3] |= (1 << uint(s.first&0x7))
 	s.inuse++
 
 	// find a next object that are unused
@@ -307,49 +903,114 @@ func (s *slab) alloc() (obj interface{}) {
 	return
 }
 
-func (s *slab) free(optr uintptr) bool {
-	if optr > s.emem {
-		return false
+// whether `optr` points within object array
+func (s *slab) owns(optr uintptr) bool {
+	return optr >= s.smem && optr <= s.last()
+}
+
+// return the last address of object array
+func (s *slab) last() uintptr {
+	return s.emem + s.objsize - 1
+}
+
+// return index of object that `optr` points
+func (s *slab) indexOf(optr uintptr) (int, error) {
+	if !s.owns(optr) {
+		return -1, ErrNotOwned
 	}
 
 	// search target object
 	lptr := optr - s.smem
 	iptr := lptr / s.objsize
 	if lptr != iptr*s.objsize {
-		return false
+		return -1, ErrMisaligned
+	}
+	return int(iptr), nil
+}
+
+func (s *slab) free(optr uintptr) (int, error) {
+	i, err := s.indexOf(optr)
+	if err != nil {
+		return -1, err
 	}
 
-	i := int(iptr)
-	s.bufctl[i>>3] ^= (1 << uint(i&0x7))
+	bit := byte(1 << uint(i&0x7))
+	if s.bufctl[i>>3]&bit == 0 {
+		return -1, ErrDoubleFree
+	}
+	s.bufctl[i>>3] &^= bit
 	s.inuse--
+	if s.gens != nil {
+		// invalidate handles of the object
+		s.gens[i]++
+	}
 	if s.first > i {
 		s.first = i
 	}
-	return true
+	return i, nil
 }
 
-func (s *slab) destroy(dtor Destructor) {
+// call destructor for objects that are constructed or have been allocated
+func (s *slab) destroy(dtor DestructorE, errs []error) []error {
 	if dtor != nil {
-		for _, o := range s.chunk {
-			dtor(o)
+		for i, o := range s.chunk {
+			if s.touched[i>>3]&(1<<uint(i&0x7)) == 0 {
+				continue
+			}
+			if err := dtor(o, s.bufctl[i>>3]&(1<<uint(i&0x7)) != 0); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
+	if s.mapping != nil {
+		if err := munmap(s.mapping); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
 }
 
-func newSlab(otype reflect.Type, size int, ctor Constructor) *slab {
+// create a slab, return nil if `size` is invalid or memory can not be mapped
+func newSlab(otype reflect.Type, size int, ctor Constructor, redzone, offheap bool) *slab {
 	if mod := size & 0x07; size < 1 || mod != 0 {
 		return nil
 	}
 
-	slice := reflect.MakeSlice(reflect.SliceOf(otype), size, size)
-	chunk := make([]interface{}, size)
-	for i := 0; i < size; i++ {
-		chunk[i] = slice.Index(i).Addr().Interface()
+	etype := otype
+	if redzone {
+		etype = redzoneType(otype)
 	}
 
-	if ctor != nil {
-		for _, o := range chunk {
-			ctor(o)
+	var mem []byte
+	if offheap {
+		var err error
+		if mem, err = mmap(size * int(etype.Size())); err != nil {
+			return nil
+		}
+	}
+	s := slabOf(etype, size, mem, redzone)
+	s.bufctl = make([]byte, size>>3)
+	s.mapping = mem
+	s.construct(ctor)
+	return s
+}
+
+// create a slab over object array `mem`, or array allocated from Go heap if nil.
+// bufctl must be set by caller.
+func slabOf(etype reflect.Type, size int, mem []byte, redzone bool) *slab {
+	var slice reflect.Value
+	if mem != nil {
+		array := reflect.ArrayOf(size, etype)
+		slice = reflect.NewAt(array, unsafe.Pointer(&mem[0])).Elem().Slice(0, size)
+	} else {
+		slice = reflect.MakeSlice(reflect.SliceOf(etype), size, size)
+	}
+	chunk := make([]interface{}, size)
+	for i := 0; i < size; i++ {
+		if redzone {
+			chunk[i] = slice.Index(i).Field(0).Addr().Interface()
+		} else {
+			chunk[i] = slice.Index(i).Addr().Interface()
 		}
 	}
 
@@ -357,33 +1018,30 @@ func newSlab(otype reflect.Type, size int, ctor Constructor) *slab {
 		total:   size,
 		inuse:   0,
 		first:   0,
-		objsize: otype.Size(),
+		objsize: etype.Size(),
 		smem:    slice.Index(0).UnsafeAddr(),
 		emem:    slice.Index(size - 1).UnsafeAddr(),
-		bufctl:  make([]byte, size>>3),
 		chunk:   chunk,
+		touched: make([]byte, (size+7)>>3),
+		mem:     unsafe.Slice((*byte)(slice.UnsafePointer()), uintptr(size)*etype.Size()),
 	}
 }
 
-type slabs []*slab
-
-func (s slabs) find(p uintptr) int {
-	return sort.Search(len(s), func(i int) bool { return s[i].smem > p }) - 1
-}
-
-func (s *slabs) insert(o *slab) {
-	i := s.find(o.smem) + 1
-	*s = append((*s), nil)
-	copy((*s)[i+1:], (*s)[i:])
-	(*s)[i] = o
+// call constructor for all objects
+func (s *slab) construct(ctor Constructor) {
+	if ctor != nil {
+		for _, o := range s.chunk {
+			ctor(o)
+		}
+		s.touchAll()
+	}
 }
 
-func (s *slabs) pop(i int) (o *slab) {
-	o = (*s)[i]
-	copy((*s)[i:], (*s)[i+1:])
-	(*s)[len(*s)-1] = nil
-	*s = (*s)[:len(*s)-1]
-	return
+// mark all objects as constructed or allocated
+func (s *slab) touchAll() {
+	for i := range s.touched {
+		s.touched[i] = 0xff
+	}
 }
 
 func init() {
@@ -1,6 +1,8 @@
 package slabgo_test
 
 import (
+	"errors"
+	"math/rand"
 	"reflect"
 	"testing"
 	"unsafe"
@@ -51,6 +53,17 @@ func checkGrow(t *testing.T, name string, act, exp int) {
 	}
 }
 
+func checkGrowReap(t *testing.T, name string, c *slabgo.Cache, grows, reaps uint64) {
+	var act slabgo.CacheStats
+	c.ReadStats(&act)
+	if act.Grows != grows {
+		t.Errorf("%s - grows: expected [%d], actual [%d]", name, grows, act.Grows)
+	}
+	if act.Reaps != reaps {
+		t.Errorf("%s - reaps: expected [%d], actual [%d]", name, reaps, act.Reaps)
+	}
+}
+
 func checkReap(t *testing.T, name string, act, exp int) {
 	if act != exp {
 		t.Errorf("%s - reap: expected [%d], actual [%d]", name, exp, act)
@@ -298,6 +311,7 @@ func TestSlabFree(t *testing.T) {
 	checkReap(t, name, rnum, 3)
 	checkConstruct(t, name, cnum, objLen*3)
 	checkDestruct(t, name, dnum, objLen*3)
+	checkGrowReap(t, name, cache, 3, 3)
 
 	if cache.Free(foos[0]) {
 		t.Errorf("Free() - double free")
@@ -493,4 +507,245 @@ func TestSlabReAllocate(t *testing.T) {
 	checkReap(t, name, rnum, 2)
 	checkConstruct(t, name, cnum, objLen*3)
 	checkDestruct(t, name, dnum, 0)
+	checkGrowReap(t, name, cache, 3, 0)
+}
+
+func TestSlabFreePtrErr(t *testing.T) {
+	var foo Foo
+	var foos []*Foo
+	var stats slabgo.CacheStats
+
+	objLen := 8
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{
+		ObjLen: objLen,
+		Grower: func(s *slabgo.CacheStats) int { return 1 },
+		Reaper: func(s *slabgo.CacheStats) int { return 0 },
+	})
+
+	for i := 0; i < objLen*2; i++ {
+		if f, ok := cache.Alloc().(*Foo); ok {
+			foos = append(foos, f)
+		}
+	}
+
+	checkErr := func(name string, act, exp error) {
+		if !errors.Is(act, exp) {
+			t.Errorf("FreePtrErr() %s - expected [%v], actual [%v]", name, exp, act)
+		}
+	}
+
+	checkErr("nil", cache.FreePtrErr(nil), slabgo.ErrNotOwned)
+	checkErr("not allocated", cache.FreePtrErr(unsafe.Pointer(&foo)), slabgo.ErrNotOwned)
+	checkErr("misaligned", cache.FreePtrErr(unsafe.Add(unsafe.Pointer(foos[0]), 1)), slabgo.ErrMisaligned)
+	checkErr("misaligned last", cache.FreePtrErr(unsafe.Add(unsafe.Pointer(foos[objLen-1]), 1)), slabgo.ErrMisaligned)
+
+	// double free within full and partial slab
+	checkErr("1st", cache.FreePtrErr(unsafe.Pointer(foos[0])), nil)
+	checkErr("double free partial", cache.FreePtrErr(unsafe.Pointer(foos[0])), slabgo.ErrDoubleFree)
+	if cache.FreePtr(unsafe.Pointer(foos[0])) {
+		t.Error("FreePtr() - double free partial")
+	}
+
+	// double free within empty slab
+	for _, f := range foos[objLen:] {
+		checkErr("2nd", cache.FreePtrErr(unsafe.Pointer(f)), nil)
+	}
+	checkErr("double free empty", cache.FreePtrErr(unsafe.Pointer(foos[objLen])), slabgo.ErrDoubleFree)
+	checkErr("misaligned empty", cache.FreePtrErr(unsafe.Add(unsafe.Pointer(foos[objLen]), 1)), slabgo.ErrMisaligned)
+
+	name := "FreePtrErr()"
+	stats.TotalSlabs = 2
+	stats.InuseSlabs = 1
+	stats.TotalObjs = objLen * 2
+	stats.InuseObjs = objLen - 1
+	stats.Allocs = uint64(objLen * 2)
+	stats.Frees = uint64(objLen + 1)
+	checkStats(t, name, cache, &stats)
+
+	// a freed object is allocated again
+	if f := cache.Alloc().(*Foo); f != foos[0] {
+		t.Error("Alloc() - not reused after double free")
+	}
+}
+
+func TestSlabPanicOnInvalidFree(t *testing.T) {
+	var foo Foo
+	for _, concurrent := range []bool{false, true} {
+		cache := slabgo.NewCache(foo, slabgo.CacheOptions{PanicOnInvalidFree: true, Concurrent: concurrent})
+		f := cache.Alloc().(*Foo)
+		if !cache.FreePtr(unsafe.Pointer(f)) {
+			t.Fatal("FreePtr() - failed")
+		}
+
+		func() {
+			defer func() {
+				err, ok := recover().(error)
+				if !ok || !errors.Is(err, slabgo.ErrDoubleFree) {
+					t.Errorf("FreePtr() - expected panic [%v], actual [%v]", slabgo.ErrDoubleFree, err)
+				}
+			}()
+			cache.FreePtr(unsafe.Pointer(f))
+			t.Error("FreePtr() - not panic")
+		}()
+	}
+}
+
+func TestSlabNewCacheE(t *testing.T) {
+	var foo Foo
+	for _, c := range []struct {
+		name string
+		obj  interface{}
+		opts slabgo.CacheOptions
+		err  error
+	}{
+		{"valid", foo, slabgo.CacheOptions{}, nil},
+		{"nil", nil, slabgo.CacheOptions{}, slabgo.ErrInvalidObject},
+		{"zero size", struct{}{}, slabgo.CacheOptions{}, slabgo.ErrZeroSize},
+		{"objlen", foo, slabgo.CacheOptions{ObjLen: -8}, slabgo.ErrInvalidOptions},
+		{"maxobjs", foo, slabgo.CacheOptions{MaxObjs: -1}, slabgo.ErrInvalidOptions},
+		{"maxbytes", foo, slabgo.CacheOptions{MaxBytes: 1}, slabgo.ErrInvalidOptions},
+	} {
+		cache, err := slabgo.NewCacheE(c.obj, c.opts)
+		if !errors.Is(err, c.err) {
+			t.Errorf("NewCacheE() %s - expected [%v], actual [%v]", c.name, c.err, err)
+		}
+		if (cache == nil) != (c.err != nil) {
+			t.Errorf("NewCacheE() %s - unexpected cache %v", c.name, cache)
+		}
+		if cache := slabgo.NewCache(c.obj, c.opts); (cache == nil) != (c.err != nil) {
+			t.Errorf("NewCache() %s - unexpected cache %v", c.name, cache)
+		}
+	}
+}
+
+func TestSlabTryAlloc(t *testing.T) {
+	var foo Foo
+
+	checkErr := func(name string, cache *slabgo.Cache, exp error) {
+		obj, err := cache.TryAlloc()
+		if !errors.Is(err, exp) {
+			t.Errorf("TryAlloc() %s - expected [%v], actual [%v]", name, exp, err)
+		}
+		if (obj == nil) != (exp != nil) {
+			t.Errorf("TryAlloc() %s - unexpected object %v", name, obj)
+		}
+	}
+
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, MaxObjs: 1})
+	checkErr("valid", cache, nil)
+	checkErr("limit objs", cache, slabgo.ErrLimit)
+
+	cache = slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, MaxObjs: 20})
+	cache.AllocN(make([]interface{}, 16))
+	cache.Free(cache.Alloc())
+	checkErr("after free", cache, nil)
+
+	cache = slabgo.NewCache(foo, slabgo.CacheOptions{
+		Grower: func(s *slabgo.CacheStats) int { return 0 },
+	})
+	checkErr("grower", cache, slabgo.ErrGrowerDeclined)
+
+	for _, opts := range []slabgo.CacheOptions{{}, {Concurrent: true}} {
+		cache = slabgo.NewCache(foo, opts)
+		checkErr("before destroy", cache, nil)
+		cache.Destroy()
+		checkErr("destroyed", cache, slabgo.ErrDestroyed)
+		if n := cache.AllocN(make([]interface{}, 4)); n != 0 {
+			t.Errorf("AllocN() destroyed - expected [0], actual [%d]", n)
+		}
+		var stats slabgo.CacheStats
+		if cache.ReadStats(&stats); stats.FailedAllocs != 5 {
+			t.Errorf("AllocN() destroyed - failed allocs: expected [5], actual [%d]", stats.FailedAllocs)
+		}
+	}
+}
+
+func TestSlabStatsHistory(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	objLen := 8
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{
+		ObjLen:  objLen,
+		MaxObjs: objLen * 2,
+		Reaper:  func(s *slabgo.CacheStats) int { return 1 },
+	})
+
+	var objs []interface{}
+	for i := 0; i < objLen+objLen/2; i++ {
+		objs = append(objs, cache.Alloc())
+	}
+	cache.ReadStats(&stats)
+	if stats.PartialSlabs != 1 || stats.Fragmentation != 0.25 {
+		t.Errorf("Alloc() - partial slabs, fragmentation: expected [1, 0.25], actual [%d, %f]",
+			stats.PartialSlabs, stats.Fragmentation)
+	}
+
+	for _, o := range objs {
+		cache.Free(o)
+	}
+	cache.AllocN(make([]interface{}, objLen*3))
+
+	name := "history"
+	cache.ReadStats(&stats)
+	if stats.PeakInuseObjs != objLen*2 || stats.PeakTotalSlabs != 2 {
+		t.Errorf("%s - peak objs, slabs: expected [%d, 2], actual [%d, %d]",
+			name, objLen*2, stats.PeakInuseObjs, stats.PeakTotalSlabs)
+	}
+	if stats.SlabsCreated != 4 || stats.SlabsDestroyed != 2 {
+		t.Errorf("%s - slabs created, destroyed: expected [4, 2], actual [%d, %d]",
+			name, stats.SlabsCreated, stats.SlabsDestroyed)
+	}
+	if stats.FailedAllocs != uint64(objLen) {
+		t.Errorf("%s - failed allocs: expected [%d], actual [%d]", name, objLen, stats.FailedAllocs)
+	}
+	if stats.PartialSlabs != 0 || stats.Fragmentation != 0 {
+		t.Errorf("%s - partial slabs, fragmentation: expected [0, 0], actual [%d, %f]",
+			name, stats.PartialSlabs, stats.Fragmentation)
+	}
+
+	cache.Alloc()
+	if cache.ReadStats(&stats); stats.FailedAllocs != uint64(objLen+1) {
+		t.Errorf("Alloc() - failed allocs: expected [%d], actual [%d]", objLen+1, stats.FailedAllocs)
+	}
+}
+
+func TestSlabManySlabs(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	objLen, slabs := 8, 1000
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: objLen})
+
+	foos := make([]*Foo, objLen*slabs)
+	for i := range foos {
+		foos[i] = cache.Alloc().(*Foo)
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(foos), func(i, j int) { foos[i], foos[j] = foos[j], foos[i] })
+
+	for i, f := range foos[:len(foos)/2] {
+		if err := cache.FreePtrErr(unsafe.Add(unsafe.Pointer(f), 1)); !errors.Is(err, slabgo.ErrMisaligned) {
+			t.Fatalf("FreePtrErr() - misaligned at %d: %v", i, err)
+		}
+		if err := cache.FreePtrErr(unsafe.Pointer(f)); err != nil {
+			t.Fatalf("FreePtrErr() - failed at %d: %v", i, err)
+		}
+		if err := cache.FreePtrErr(unsafe.Pointer(f)); !errors.Is(err, slabgo.ErrDoubleFree) {
+			t.Fatalf("FreePtrErr() - double free at %d: %v", i, err)
+		}
+	}
+	for _, f := range foos[len(foos)/2:] {
+		cache.FreePtr(unsafe.Pointer(f))
+	}
+
+	cache.ReadStats(&stats)
+	if stats.InuseSlabs != 0 || stats.TotalSlabs < slabs {
+		t.Errorf("FreePtr() - unexpected stats %+v", stats)
+	}
+	if err := cache.FreePtrErr(unsafe.Pointer(foos[0])); !errors.Is(err, slabgo.ErrDoubleFree) {
+		t.Errorf("FreePtrErr() - empty slab: %v", err)
+	}
+	if err := cache.FreePtrErr(unsafe.Pointer(&foo)); !errors.Is(err, slabgo.ErrNotOwned) {
+		t.Errorf("FreePtrErr() - not owned: %v", err)
+	}
 }
@@ -0,0 +1,199 @@
+package slabgo
+
+import (
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Allocate `n` contiguous objects from a slab, and return them as a slice of object type.
+// return nil if `n` is not from 1 to `Cache.ObjectLen`, there is no slab that has `n` contiguous
+// unused objects, or the cache is on debug mode (objects are separated by red zones).
+// A slice allocated by this must be freed by `Cache.FreeSlice`, or its objects one by one.
+//
+// NOTE: This function bypasses magazines of a concurrent cache.
+// Partial slabs are searched regardless of `CacheOptions.Policy`.
+func (c *Cache) AllocSlice(n int) interface{} {
+	objs := c.allocSlice(n)
+	if objs == nil {
+		return nil
+	}
+	slice := reflect.New(reflect.SliceOf(c.objType))
+	*(*sliceHeader)(slice.UnsafePointer()) = sliceHeader{
+		data: reflect.ValueOf(objs[0]).UnsafePointer(),
+		len:  n,
+		cap:  n,
+	}
+	return slice.Elem().Interface()
+}
+
+// layout of slice
+type sliceHeader struct {
+	data unsafe.Pointer
+	len  int
+	cap  int
+}
+
+// allocate `n` contiguous objects, and return pointers of them that must not be modified
+func (c *Cache) allocSlice(n int) []interface{} {
+	if n < 1 || n > c.objLen || c.debug {
+		return nil
+	}
+
+	c.lock()
+	objs := c.allocRun(n)
+	c.unlock()
+
+	if objs == nil {
+		atomic.AddUint64(&c.failed, 1)
+		return nil
+	}
+	if c.prepares {
+		for _, o := range objs {
+			c.prepare(o)
+		}
+	}
+	return objs
+}
+
+// allocate `n` contiguous objects, return them or nil
+func (c *Cache) allocRun(n int) []interface{} {
+	if n > c.maxObjs-c.inuseObjs || c.destroyed {
+		// reached the limit, or destroyed
+		return nil
+	}
+
+	s, i := c.findRun(n)
+	if s == nil {
+		return nil
+	}
+	s.allocRun(i, n)
+	if c.track {
+		s.sites[i] = site{}
+		runtime.Callers(2, s.sites[i][:])
+		for k := 1; k < n; k++ {
+			s.sites[i+k] = s.sites[i]
+		}
+	}
+	c.touch(s)
+
+	c.inuseObjs += n
+	c.allocs += uint64(n)
+	if c.inuseObjs > c.peakObjs {
+		c.peakObjs = c.inuseObjs
+	}
+	return s.chunk[i : i+n]
+}
+
+// return a partial slab that has `n` contiguous unused objects and index of the first, or nil
+func (c *Cache) findRun(n int) (*slab, int) {
+	for s := c.partial.head; s != nil; s = s.next {
+		if s.total-s.inuse >= n {
+			if i := s.findRun(n); i >= 0 {
+				return s, i
+			}
+		}
+	}
+
+	if c.empty.len == 0 && c.grow() == 0 {
+		// there is no available slab
+		return nil, -1
+	}
+	s := c.empty.remove(c.empty.head)
+	c.toPartial(s)
+	return s, 0
+}
+
+// return index of the first of `n` contiguous unused objects, or -1
+func (s *slab) findRun(n int) int {
+	run := 0
+	for i := s.first; i < s.total; i++ {
+		if b := s.bufctl[i>>3]; i&0x7 == 0 && b == 0xff {
+			// skip used objects
+			i += 7
+			run = 0
+		} else if b&(1<<uint(i&0x7)) != 0 {
+			run = 0
+		} else if run++; run == n {
+			return i - n + 1
+		}
+	}
+	return -1
+}
+
+// mark `n` objects from `i` as used
+func (s *slab) allocRun(i, n int) {
+	for j := i; j < i+n; j++ {
+		s.bufctl[j>>3] |= 1 << uint(j&0x7)
+		s.touched[j>>3] |= 1 << uint(j&0x7)
+		s.own(j)
+	}
+	s.inuse += n
+	if s.first != i {
+		return
+	}
+
+	// find a next object that are unused, objects before it are all used
+	for j := (i + n) >> 3; j < len(s.bufctl); j++ {
+		if s.bufctl[j] != 0xff {
+			s.first = j<<3 + int(ntzMatrix[s.bufctl[j]])
+			return
+		}
+	}
+	s.first = len(s.bufctl) << 3
+}
+
+// Return objects of a slice allocated by `Cache.AllocSlice` to cache.
+// `slice` must have the same start and length.
+func (c *Cache) FreeSlice(slice interface{}) bool {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice || v.Type().Elem() != c.objType {
+		// invalid type
+		return c.freeFailed(0, ErrNotOwned) == nil
+	}
+	return c.freeSlice(v.Pointer(), v.Len()) == nil
+}
+
+// return `n` contiguous objects from `ptr` to slabs
+func (c *Cache) freeSlice(ptr uintptr, n int) error {
+	c.lock()
+	s, err := c.checkRun(ptr, n)
+	if err == nil {
+		for k := 0; k < n; k++ {
+			c.free(ptr + uintptr(k)*s.objsize)
+		}
+	}
+	errs := c.takeCorruptions()
+	c.unlock()
+
+	c.report(errs)
+	if err != nil {
+		return c.freeFailed(ptr, err)
+	}
+	if atomic.LoadInt32(&c.waiters) > 0 {
+		c.wakeup()
+	}
+	return nil
+}
+
+// return a slab that `n` contiguous objects from `ptr` are in use within
+func (c *Cache) checkRun(ptr uintptr, n int) (*slab, error) {
+	s := c.addrs.get(ptr)
+	if s == nil || n < 1 {
+		return nil, ErrNotOwned
+	}
+	i, err := s.indexOf(ptr)
+	if err != nil {
+		return nil, err
+	} else if i+n > s.total {
+		return nil, ErrNotOwned
+	}
+	for j := i; j < i+n; j++ {
+		// objects held by magazines are already freed
+		if s.bufctl[j>>3]&(1<<uint(j&0x7)) == 0 || (s.owned != nil && !s.isOwned(j)) {
+			return nil, ErrDoubleFree
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,104 @@
+package slabgo_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+func TestAllocSlice(t *testing.T) {
+	objLen := 16
+	for _, opts := range []slabgo.CacheOptions{{ObjLen: objLen}, {ObjLen: objLen, Concurrent: true}} {
+		opts.Grower = func(s *slabgo.CacheStats) int { return 1 }
+		cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{CacheOptions: opts})
+
+		a := cache.AllocSlice(5)
+		if len(a) != 5 || cap(a) != 5 {
+			t.Fatalf("AllocSlice() - len, cap: expected [5, 5], actual [%d, %d]", len(a), cap(a))
+		}
+		for i := range a {
+			a[i].count = int64(i)
+		}
+		b := cache.AllocSlice(objLen)
+		c := cache.AllocSlice(objLen - 5)
+		if b == nil || c == nil {
+			t.Fatal("AllocSlice() - no slice")
+		}
+		if uintptr(unsafe.Pointer(&c[0])) != uintptr(unsafe.Pointer(&a[4]))+unsafe.Sizeof(a[4]) {
+			t.Error("AllocSlice() - slice is not placed after the previous one")
+		}
+		checkStats(t, "AllocSlice()", cache.Cache(), &slabgo.CacheStats{
+			TotalSlabs: 2, InuseSlabs: 2, TotalObjs: objLen * 2, InuseObjs: objLen * 2, Allocs: uint64(objLen * 2),
+		})
+		for _, n := range []int{0, 1, objLen + 1} {
+			if s := cache.AllocSlice(n); n > 0 && n <= objLen && s == nil {
+				t.Errorf("AllocSlice(%d) - no slice", n)
+			} else if (n < 1 || n > objLen) && s != nil {
+				t.Errorf("AllocSlice(%d) - unexpected slice", n)
+			} else if s != nil {
+				cache.FreeSlice(s)
+			}
+		}
+
+		// an object of slice can be freed alone
+		cache.Free(&c[2])
+		if cache.FreeSlice(c) {
+			t.Error("FreeSlice() - freed objects are freed again")
+		}
+		if !cache.FreeSlice(c[3:]) || !cache.FreeSlice(c[:2]) || !cache.FreeSlice(a) {
+			t.Error("FreeSlice() - failed to free")
+		}
+		if a[4].count != 4 {
+			t.Error("FreeSlice() - objects are modified")
+		}
+		cache.Cache().Shrink()
+		checkStats(t, "FreeSlice()", cache.Cache(), &slabgo.CacheStats{
+			TotalSlabs: 1, InuseSlabs: 1, TotalObjs: objLen, InuseObjs: objLen,
+			Allocs: uint64(objLen*2 + 1), Frees: uint64(objLen + 1),
+		})
+		if err := cache.Destroy(); err == nil {
+			t.Error("Destroy() - objects in use are not reported")
+		}
+	}
+}
+
+func TestAllocSliceRun(t *testing.T) {
+	objLen := 16
+	cache := slabgo.NewCache(Foo{}, slabgo.CacheOptions{
+		ObjLen: objLen,
+		Grower: func(s *slabgo.CacheStats) int { return 1 },
+	})
+
+	var objs []*Foo
+	for i := 0; i < objLen; i++ {
+		objs = append(objs, cache.Alloc().(*Foo))
+	}
+	for _, i := range []int{3, 5, 6, 7, 12} {
+		cache.FreePtr(unsafe.Pointer(objs[i]))
+	}
+
+	s, ok := cache.AllocSlice(3).([]Foo)
+	if !ok || &s[0] != objs[5] {
+		t.Errorf("AllocSlice() - unexpected position")
+	}
+	if s, ok := cache.AllocSlice(2).([]Foo); !ok || &s[0] == objs[3] || &s[0] == objs[12] {
+		t.Errorf("AllocSlice() - slice is placed in fragments")
+	}
+	checkStats(t, "AllocSlice()", cache, &slabgo.CacheStats{
+		TotalSlabs: 2, InuseSlabs: 2, TotalObjs: objLen * 2, InuseObjs: objLen - 5 + 3 + 2,
+		Allocs: uint64(objLen + 5), Frees: 5,
+	})
+
+	if cache.FreeSlice(objs) || cache.FreeSlice([]Foo{}) {
+		t.Error("FreeSlice() - invalid slice is freed")
+	}
+	if !cache.FreeSlice(s) {
+		t.Error("FreeSlice() - failed to free")
+	}
+
+	debug := slabgo.NewCache(Foo{}, slabgo.CacheOptions{ObjLen: objLen, Debug: true})
+	if debug.AllocSlice(2) != nil {
+		t.Error("AllocSlice() - slice is allocated on debug mode")
+	}
+}
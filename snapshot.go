@@ -0,0 +1,353 @@
+package slabgo
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// ErrInvalidSnapshot is returned by `Cache.Restore` if a snapshot is broken
+var ErrInvalidSnapshot = errors.New("slabgo: invalid snapshot")
+
+// Encoder writes values to a snapshot
+type Encoder interface {
+	Encode(v interface{}) error
+}
+
+// Decoder reads values from a snapshot
+type Decoder interface {
+	Decode(v interface{}) error
+}
+
+// Codec encodes objects of snapshots
+type Codec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// GobCodec encodes objects with encoding/gob (default).
+// NOTE: Only exported fields are encoded.
+var GobCodec Codec = gobCodec{}
+
+// BinaryCodec encodes objects with encoding/binary in little endian.
+// Objects must be fixed-size data whose fields are exported,
+// otherwise NewCacheE returns ErrInvalidOptions.
+var BinaryCodec Codec = binaryCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder {
+	return gob.NewEncoder(w)
+}
+
+func (gobCodec) NewDecoder(r io.Reader) Decoder {
+	return gob.NewDecoder(r)
+}
+
+type binaryCodec struct{}
+
+type binaryEncoder struct {
+	w io.Writer
+}
+
+type binaryDecoder struct {
+	r io.Reader
+}
+
+func (binaryCodec) NewEncoder(w io.Writer) Encoder {
+	return binaryEncoder{w}
+}
+
+func (binaryCodec) NewDecoder(r io.Reader) Decoder {
+	return binaryDecoder{r}
+}
+
+func (e binaryEncoder) Encode(v interface{}) error {
+	return binary.Write(e.w, binary.LittleEndian, v)
+}
+
+func (d binaryDecoder) Decode(v interface{}) error {
+	return binary.Read(d.r, binary.LittleEndian, v)
+}
+
+// whether encoding/binary can write and read values of `t`
+func binaryEncodable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return binaryEncodable(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			// blank fields are skipped
+			if f := t.Field(i); f.Name != "_" && (!f.IsExported() || !binaryEncodable(f.Type)) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// decoder that returns ErrInvalidSnapshot instead of panic,
+// and wraps errors with ErrInvalidSnapshot after the header is read
+type safeDecoder struct {
+	Decoder
+	header bool // whether the header has been read
+}
+
+func (d *safeDecoder) Decode(v interface{}) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = ErrInvalidSnapshot
+		} else if err != nil && d.header && !errors.Is(err, ErrInvalidSnapshot) {
+			// such as a truncated snapshot
+			err = fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+		}
+	}()
+	return d.Decoder.Decode(v)
+}
+
+const snapshotVersion = 1
+
+// maximum length of object array within a slab of snapshot, so as not to trust a broken header
+const maxSnapshotObjLen = 1 << 24
+
+// Records of a snapshot are encoded in order:
+//
+//	snapshotHeader
+//	snapshotSlab, generations of all slots ([ObjLen]uint8),
+//	and snapshotSlot and object for each object of the slab
+//	...
+type snapshotHeader struct {
+	Version uint32
+	ObjLen  uint32
+	Slabs   uint32
+}
+
+type snapshotSlab struct {
+	ID   uint32
+	Objs uint32
+}
+
+type snapshotSlot struct {
+	Index uint32
+}
+
+// objects in use within a slab
+type slabObjs struct {
+	id    uint32
+	gens  []uint8
+	slots []snapshotSlot
+	objs  []interface{}
+}
+
+// Write all objects in use to `w` with `CacheOptions.Codec`.
+// Objects held by magazines are not written.
+//
+// NOTE: Objects are encoded without lock, so that they must not be modified during Snapshot.
+func (c *Cache) Snapshot(w io.Writer) error {
+	ss := c.slabObjects()
+	enc := c.codec.NewEncoder(w)
+	if err := enc.Encode(&snapshotHeader{
+		Version: snapshotVersion,
+		ObjLen:  uint32(c.objLen),
+		Slabs:   uint32(len(ss)),
+	}); err != nil {
+		return err
+	}
+	for _, s := range ss {
+		if err := enc.Encode(&snapshotSlab{ID: s.id, Objs: uint32(len(s.objs))}); err != nil {
+			return err
+		}
+		if err := enc.Encode(&s.gens); err != nil {
+			return err
+		}
+		for i, o := range s.objs {
+			if err := enc.Encode(&s.slots[i]); err != nil {
+				return err
+			}
+			if err := enc.Encode(o); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// return objects in use by slab, slabs are ordered by id
+func (c *Cache) slabObjects() []slabObjs {
+	c.lockMags()
+	defer c.unlockMags()
+	c.lock()
+	defer c.unlock()
+
+	c.drain()
+	var ss []slabObjs
+	for _, l := range []*slabList{&c.full, &c.partial} {
+		for s := l.head; s != nil; s = s.next {
+			so := slabObjs{id: s.id, gens: make([]uint8, s.total)}
+			copy(so.gens, s.gens)
+			for i := 0; i < s.total; i++ {
+				if s.bufctl[i>>3]&(1<<uint(i&0x7)) != 0 {
+					so.slots = append(so.slots, snapshotSlot{Index: uint32(i)})
+					so.objs = append(so.objs, s.chunk[i])
+				}
+			}
+			ss = append(ss, so)
+		}
+	}
+	// slabs without id are the last
+	sort.SliceStable(ss, func(i, j int) bool {
+		return ss[i].id-1 < ss[j].id-1
+	})
+	return ss
+}
+
+// Read objects from a snapshot written by `Cache.Snapshot`, and allocate them.
+// Objects are restored at the same positions within slabs if `CacheOptions.ObjLen` is same,
+// so that handles remain valid. OnAlloc is called for each restored object.
+// Empty slabs are destroyed before that, except slabs of a persistent cache.
+// return ErrInUse if any object is in use, ErrLimit if objects exceed the limit of cache,
+// or an error wrapping ErrInvalidSnapshot if the snapshot is broken or truncated.
+// Objects restored before an error remain allocated.
+func (c *Cache) Restore(r io.Reader) error {
+	objs, errs, err := c.restoreLocked(r)
+	c.report(errs)
+	if c.onAlloc != nil {
+		for _, o := range objs {
+			c.onAlloc(o)
+		}
+	}
+	return err
+}
+
+// restore a snapshot with both locks, return objects restored and corruptions detected
+func (c *Cache) restoreLocked(r io.Reader) ([]interface{}, []error, error) {
+	c.lockMags()
+	defer c.unlockMags()
+	c.lock()
+	defer c.unlock()
+
+	objs, err := c.restoreSnapshot(r)
+	return objs, c.takeCorruptions(), err
+}
+
+func (c *Cache) restoreSnapshot(r io.Reader) (objs []interface{}, err error) {
+	if c.destroyed {
+		return nil, ErrDestroyed
+	}
+	c.drain()
+	if c.inuseObjs > 0 {
+		return nil, ErrInUse
+	}
+	if n := c.empty.len; n > 0 && c.file == nil {
+		// release ids for slabs of snapshot
+		for c.empty.head != nil {
+			c.destroySlab(c.empty.head, nil)
+		}
+		c.dropped += uint64(n)
+	}
+
+	dec := &safeDecoder{Decoder: c.codec.NewDecoder(r)}
+	var h snapshotHeader
+	if err := dec.Decode(&h); err != nil {
+		return nil, err
+	}
+	if h.Version != snapshotVersion || h.ObjLen == 0 || h.ObjLen&0x07 != 0 || h.ObjLen > maxSnapshotObjLen {
+		return nil, ErrInvalidSnapshot
+	}
+	dec.header = true
+	for n := 0; n < int(h.Slabs); n++ {
+		var hs snapshotSlab
+		if err := dec.Decode(&hs); err != nil {
+			return objs, err
+		}
+		gens := make([]uint8, h.ObjLen)
+		if err := dec.Decode(&gens); err != nil {
+			return objs, err
+		}
+		if int(hs.Objs) > c.maxObjs-c.inuseObjs {
+			return objs, ErrLimit
+		}
+		if int(h.ObjLen) == c.objLen {
+			objs, err = c.restoreSlab(dec, &hs, gens, objs)
+		} else {
+			// positions are not restored
+			objs, err = c.restoreObjs(dec, &hs, objs)
+		}
+		if err != nil {
+			return objs, err
+		}
+	}
+	return objs, nil
+}
+
+// restore objects at the same positions within a new slab
+func (c *Cache) restoreSlab(dec Decoder, hs *snapshotSlab, gens []uint8, objs []interface{}) ([]interface{}, error) {
+	if c.full.len+c.partial.len+c.empty.len >= c.maxSlabs {
+		return objs, ErrLimit
+	}
+	s := c.newSlab()
+	if s == nil {
+		return objs, ErrLimit
+	}
+	c.addrs.add(s)
+	c.claimID(s, hs.ID)
+	// stale handles remain stale
+	copy(s.gens, gens)
+
+	var err error
+	for n := 0; n < int(hs.Objs); n++ {
+		var slot snapshotSlot
+		if err = dec.Decode(&slot); err != nil {
+			break
+		}
+		i := int(slot.Index)
+		if i >= s.total || s.bufctl[i>>3]&(1<<uint(i&0x7)) != 0 {
+			err = ErrInvalidSnapshot
+			break
+		}
+		s.bufctl[i>>3] |= 1 << uint(i&0x7)
+		s.touched[i>>3] |= 1 << uint(i&0x7)
+		s.own(i)
+		objs = append(objs, s.chunk[i])
+		c.allocs++
+		c.clear(s.chunk[i])
+		if err = dec.Decode(s.chunk[i]); err != nil {
+			break
+		}
+	}
+	c.restore(s)
+	return objs, err
+}
+
+// restore objects at any positions
+func (c *Cache) restoreObjs(dec Decoder, hs *snapshotSlab, objs []interface{}) ([]interface{}, error) {
+	for n := 0; n < int(hs.Objs); n++ {
+		var slot snapshotSlot
+		if err := dec.Decode(&slot); err != nil {
+			return objs, err
+		}
+		obj := c.alloc()
+		if obj == nil {
+			return objs, ErrLimit
+		}
+		ptr := addrOf(obj)
+		s := c.addrs.get(ptr)
+		i, _ := s.indexOf(ptr)
+		s.own(i)
+		objs = append(objs, obj)
+		c.clear(obj)
+		if err := dec.Decode(obj); err != nil {
+			return objs, err
+		}
+	}
+	return objs, nil
+}
@@ -0,0 +1,218 @@
+package slabgo_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/k-sone/slabgo"
+)
+
+type Point struct {
+	X, Y int64
+}
+
+func TestSnapshotHandles(t *testing.T) {
+	objLen := 8
+	for _, codec := range []slabgo.Codec{slabgo.GobCodec, slabgo.BinaryCodec} {
+		for _, opts := range []slabgo.CacheOptions{{ObjLen: objLen}, {ObjLen: objLen, Concurrent: true}} {
+			opts.Codec = codec
+			opts.Grower = func(s *slabgo.CacheStats) int { return 1 }
+			src := slabgo.NewCache(Point{}, opts)
+
+			var hs []slabgo.Handle
+			for i := 0; i < objLen*2+4; i++ {
+				h, obj := src.AllocHandle()
+				obj.(*Point).X = int64(i)
+				obj.(*Point).Y = -int64(i)
+				hs = append(hs, h)
+			}
+			for i := 0; i < len(hs); i += 3 {
+				src.FreeHandle(hs[i])
+			}
+
+			var buf bytes.Buffer
+			if err := src.Snapshot(&buf); err != nil {
+				t.Fatalf("Snapshot() - unexpected error [%v]", err)
+			}
+
+			allocated := 0
+			opts.OnAlloc = func(objp interface{}) { allocated++ }
+			dst := slabgo.NewCache(Point{}, opts)
+			if err := dst.Restore(&buf); err != nil {
+				t.Fatalf("Restore() - unexpected error [%v]", err)
+			}
+			inuse := len(hs) - (len(hs)+2)/3
+			checkStats(t, "Restore()", dst, &slabgo.CacheStats{
+				TotalSlabs: 3, InuseSlabs: 3, TotalObjs: objLen * 3, InuseObjs: inuse, Allocs: uint64(inuse),
+			})
+			if allocated != inuse {
+				t.Errorf("Restore() - OnAlloc: expected [%d], actual [%d]", inuse, allocated)
+			}
+
+			for i, h := range hs {
+				obj := dst.Deref(h)
+				if i%3 == 0 {
+					if obj != nil {
+						t.Errorf("Deref() - freed handle %d is valid", i)
+					}
+				} else if p, ok := obj.(*Point); !ok || p.X != int64(i) || p.Y != -int64(i) {
+					t.Errorf("Deref() - handle %d: unexpected object %v", i, obj)
+				}
+			}
+
+			// a free slot is reused with a new generation
+			h, _ := dst.AllocHandle()
+			for _, old := range hs {
+				if h == old {
+					t.Errorf("AllocHandle() - handle %#x is reused", h)
+				}
+			}
+		}
+	}
+}
+
+func TestSnapshotObjLen(t *testing.T) {
+	src := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Point]{CacheOptions: slabgo.CacheOptions{ObjLen: 8}})
+	sum := int64(0)
+	for i := 0; i < 20; i++ {
+		src.Alloc().X = int64(i)
+		sum += int64(i)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot() - unexpected error [%v]", err)
+	}
+
+	dst := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Point]{CacheOptions: slabgo.CacheOptions{ObjLen: 16}})
+	if err := dst.Restore(&buf); err != nil {
+		t.Fatalf("Restore() - unexpected error [%v]", err)
+	}
+	n, restored := 0, int64(0)
+	dst.Range(func(p *Point) bool {
+		n++
+		restored += p.X
+		return true
+	})
+	if n != 20 || restored != sum {
+		t.Errorf("Restore() - objects, sum: expected [20, %d], actual [%d, %d]", sum, n, restored)
+	}
+}
+
+func TestRestoreInvalid(t *testing.T) {
+	cache := slabgo.NewCache(Point{}, slabgo.CacheOptions{ObjLen: 8})
+	obj := cache.Alloc()
+
+	var buf bytes.Buffer
+	if err := cache.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot() - unexpected error [%v]", err)
+	}
+	if err := cache.Restore(bytes.NewReader(buf.Bytes())); !errors.Is(err, slabgo.ErrInUse) {
+		t.Errorf("Restore() - expected [%v], actual [%v]", slabgo.ErrInUse, err)
+	}
+
+	// empty slabs are replaced
+	cache.Free(obj)
+	if err := cache.Restore(&buf); err != nil {
+		t.Errorf("Restore() - unexpected error [%v]", err)
+	}
+	checkStats(t, "Restore()", cache, &slabgo.CacheStats{
+		TotalSlabs: 1, InuseSlabs: 1, TotalObjs: 8, InuseObjs: 1, Allocs: 2, Frees: 1,
+	})
+
+	buf.Reset()
+	gob.NewEncoder(&buf).Encode(struct{ Version, ObjLen, Slabs uint32 }{2, 8, 0})
+	if err := slabgo.NewCacheSimple(Point{}).Restore(&buf); !errors.Is(err, slabgo.ErrInvalidSnapshot) {
+		t.Errorf("Restore() - expected [%v], actual [%v]", slabgo.ErrInvalidSnapshot, err)
+	}
+	if err := slabgo.NewCacheSimple(Point{}).Restore(bytes.NewReader([]byte("broken"))); err == nil {
+		t.Error("Restore() - broken snapshot is restored")
+	}
+
+	// truncated snapshot
+	for _, codec := range []slabgo.Codec{slabgo.GobCodec, slabgo.BinaryCodec} {
+		src := slabgo.NewCache(Point{}, slabgo.CacheOptions{ObjLen: 8, Codec: codec})
+		for i := 0; i < 10; i++ {
+			src.Alloc()
+		}
+		buf.Reset()
+		if err := src.Snapshot(&buf); err != nil {
+			t.Fatalf("Snapshot() - unexpected error [%v]", err)
+		}
+		dst := slabgo.NewCache(Point{}, slabgo.CacheOptions{ObjLen: 8, Codec: codec})
+		if err := dst.Restore(bytes.NewReader(buf.Bytes()[:buf.Len()/2])); !errors.Is(err, slabgo.ErrInvalidSnapshot) {
+			t.Errorf("Restore() - truncated: expected [%v], actual [%v]", slabgo.ErrInvalidSnapshot, err)
+		}
+	}
+}
+
+type panicCodec struct{}
+
+func (panicCodec) NewEncoder(w io.Writer) slabgo.Encoder { return gob.NewEncoder(w) }
+func (panicCodec) NewDecoder(r io.Reader) slabgo.Decoder { return panicDecoder{} }
+
+type panicDecoder struct{}
+
+func (panicDecoder) Decode(v interface{}) error { panic("broken") }
+
+func TestRestoreUnsupported(t *testing.T) {
+	type private struct{ a, b int64 }
+	for _, obj := range []interface{}{private{}, struct{ P *Point }{}, 0} {
+		if _, err := slabgo.NewCacheE(obj, slabgo.CacheOptions{Codec: slabgo.BinaryCodec}); !errors.Is(err, slabgo.ErrInvalidOptions) {
+			t.Errorf("NewCacheE(%T) - expected [%v], actual [%v]", obj, slabgo.ErrInvalidOptions, err)
+		}
+	}
+	if _, err := slabgo.NewCacheE(struct{ _, X int64 }{}, slabgo.CacheOptions{Codec: slabgo.BinaryCodec}); err != nil {
+		t.Errorf("NewCacheE() - unexpected error [%v]", err)
+	}
+
+	// a panic of decoder is an invalid snapshot, and the cache is still usable
+	cache := slabgo.NewCache(Point{}, slabgo.CacheOptions{Codec: panicCodec{}, Concurrent: true})
+	if err := cache.Restore(bytes.NewReader(nil)); !errors.Is(err, slabgo.ErrInvalidSnapshot) {
+		t.Errorf("Restore() - expected [%v], actual [%v]", slabgo.ErrInvalidSnapshot, err)
+	}
+	if cache.Alloc() == nil {
+		t.Error("Alloc() - failed after Restore")
+	}
+
+	// length of object array is bounded
+	for _, objLen := range []uint32{0, 12, 1 << 31} {
+		var buf bytes.Buffer
+		gob.NewEncoder(&buf).Encode(struct{ Version, ObjLen, Slabs uint32 }{1, objLen, 1})
+		if err := slabgo.NewCacheSimple(Point{}).Restore(&buf); !errors.Is(err, slabgo.ErrInvalidSnapshot) {
+			t.Errorf("Restore() - ObjLen %d: expected [%v], actual [%v]", objLen, slabgo.ErrInvalidSnapshot, err)
+		}
+	}
+}
+
+func TestRestoreSparseDestroy(t *testing.T) {
+	opts := slabgo.CacheOptions{ObjLen: 256}
+	src := slabgo.NewCache(Point{}, opts)
+	var objs []interface{}
+	for i := 0; i < 200; i++ {
+		objs = append(objs, src.Alloc())
+	}
+	for _, o := range objs[:199] {
+		src.Free(o)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot() - unexpected error [%v]", err)
+	}
+	var d destructed
+	opts.DestructorE = d.destructor
+	dst := slabgo.NewCache(Point{}, opts)
+	if err := dst.Restore(&buf); err != nil {
+		t.Fatalf("Restore() - unexpected error [%v]", err)
+	}
+
+	// slots before the restored object have never been allocated
+	dst.Destroy()
+	if d.all != 1 || d.inuse != 1 {
+		t.Errorf("Destroy() - destructed all, inuse: expected [1, 1], actual [%d, %d]", d.all, d.inuse)
+	}
+}
@@ -0,0 +1,112 @@
+package slabgo
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const siteDepth = 32 // max depth of caller stack recorded
+
+// program counters of allocation site
+type site [siteDepth]uintptr
+
+// Objects in use grouped by allocation site
+type Leak struct {
+	Count  int             // number of objects in use
+	Frames []runtime.Frame // caller stack of allocation, from innermost
+}
+
+var pkgPrefix = reflect.TypeOf(Cache{}).PkgPath() + "."
+
+// Return objects in use grouped by allocation site, sorted by descending count.
+// It requires `CacheOptions.Track`, otherwise returns nil.
+func (c *Cache) Leaks() []Leak {
+	if !c.track {
+		return nil
+	}
+
+	counts := make(map[site]int)
+	c.lock()
+	for _, l := range []*slabList{&c.full, &c.partial} {
+		for s := l.head; s != nil; s = s.next {
+			for i := 0; i < s.total; i++ {
+				if s.bufctl[i>>3]&(1<<uint(i&0x7)) != 0 {
+					counts[s.sites[i]]++
+				}
+			}
+		}
+	}
+	c.unlock()
+
+	leaks := make([]Leak, 0, len(counts))
+	for st, n := range counts {
+		leaks = append(leaks, Leak{Count: n, Frames: st.frames()})
+	}
+	sort.Slice(leaks, func(i, j int) bool {
+		if leaks[i].Count != leaks[j].Count {
+			return leaks[i].Count > leaks[j].Count
+		}
+		return leaks[i].location() < leaks[j].location()
+	})
+	return leaks
+}
+
+// Write objects in use grouped by allocation site to `w`.
+// It requires `CacheOptions.Track`.
+func (c *Cache) DumpInuse(w io.Writer) error {
+	leaks := c.Leaks()
+
+	var total int
+	for _, l := range leaks {
+		total += l.Count
+	}
+	if _, err := fmt.Fprintf(w, "%d objects of %s in use at %d sites\n", total, c.objType, len(leaks)); err != nil {
+		return err
+	}
+
+	for _, l := range leaks {
+		if _, err := fmt.Fprintf(w, "\n%d objects allocated at:\n", l.Count); err != nil {
+			return err
+		}
+		for _, f := range l.Frames {
+			if _, err := fmt.Fprintf(w, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// return frames without ones within this package
+func (st *site) frames() (frames []runtime.Frame) {
+	n := 0
+	for n < len(st) && st[n] != 0 {
+		n++
+	}
+	if n == 0 {
+		// unknown
+		return nil
+	}
+
+	iter := runtime.CallersFrames(st[:n])
+	for {
+		f, more := iter.Next()
+		if len(frames) > 0 || !strings.HasPrefix(f.Function, pkgPrefix) {
+			frames = append(frames, f)
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+func (l *Leak) location() string {
+	if len(l.Frames) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", l.Frames[0].File, l.Frames[0].Line)
+}
@@ -0,0 +1,125 @@
+package slabgo_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/k-sone/slabgo"
+)
+
+func allocSiteA(c *slabgo.Cache) *Foo {
+	return c.Alloc().(*Foo)
+}
+
+func allocSiteB(c *slabgo.TypedCache[Foo]) *Foo {
+	return c.Alloc()
+}
+
+func TestTrackLeaks(t *testing.T) {
+	var foo Foo
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, Track: true, Concurrent: true})
+
+	var foos []*Foo
+	for i := 0; i < 20; i++ {
+		foos = append(foos, allocSiteA(cache))
+	}
+	for _, f := range foos[:5] {
+		cache.Free(f)
+	}
+
+	typed := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{
+		CacheOptions: slabgo.CacheOptions{Track: true},
+	})
+	for i := 0; i < 2; i++ {
+		allocSiteB(typed)
+	}
+
+	for _, c := range []struct {
+		name  string
+		cache *slabgo.Cache
+		count int
+		fn    string
+	}{
+		{"untyped", cache, 15, "allocSiteA"},
+		{"typed", typed.Cache(), 2, "allocSiteB"},
+	} {
+		leaks := c.cache.Leaks()
+		if len(leaks) != 1 {
+			t.Errorf("Leaks() %s - sites: expected [1], actual [%d]", c.name, len(leaks))
+			continue
+		}
+		if leaks[0].Count != c.count {
+			t.Errorf("Leaks() %s - count: expected [%d], actual [%d]", c.name, c.count, leaks[0].Count)
+		}
+		if f := leaks[0].Frames; len(f) == 0 || !strings.HasSuffix(f[0].Function, c.fn) {
+			t.Errorf("Leaks() %s - frames: expected [%s], actual %v", c.name, c.fn, f)
+		}
+	}
+}
+
+func allocDeep(c *slabgo.Cache, depth int) *Foo {
+	if depth == 0 {
+		return c.Alloc().(*Foo)
+	}
+	return allocDeep(c, depth-1)
+}
+
+func TestTrackReusedSlot(t *testing.T) {
+	var foo Foo
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, Track: true})
+
+	// the first object reuses a slot allocated from a deeper stack
+	cache.Free(allocDeep(cache, 20))
+	for i := 0; i < 2; i++ {
+		allocSiteA(cache)
+	}
+
+	leaks := cache.Leaks()
+	if len(leaks) != 1 || leaks[0].Count != 2 {
+		t.Fatalf("Leaks() - expected 2 objects at 1 site, actual %v", leaks)
+	}
+	for _, f := range leaks[0].Frames {
+		if strings.HasSuffix(f.Function, "allocDeep") {
+			t.Errorf("Leaks() - frame of previous allocation %s remains", f.Function)
+		}
+	}
+}
+
+func TestTrackDumpInuse(t *testing.T) {
+	var foo Foo
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{Track: true})
+
+	allocSiteA(cache)
+	for i := 0; i < 3; i++ {
+		cache.Alloc()
+	}
+
+	leaks := cache.Leaks()
+	if len(leaks) != 2 || leaks[0].Count != 3 || leaks[1].Count != 1 {
+		t.Fatalf("Leaks() - unexpected %v", leaks)
+	}
+
+	var buf bytes.Buffer
+	if err := cache.DumpInuse(&buf); err != nil {
+		t.Fatalf("DumpInuse() - failed: %v", err)
+	}
+	out := buf.String()
+	for _, s := range []string{"4 objects of slabgo_test.Foo in use at 2 sites", "3 objects", "1 objects", "allocSiteA", "TestTrackDumpInuse", "track_test.go"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("DumpInuse() - %q not found in\n%s", s, out)
+		}
+	}
+	if strings.Contains(out, "(*Cache).Alloc") {
+		t.Errorf("DumpInuse() - internal frames found in\n%s", out)
+	}
+}
+
+func TestTrackDisabled(t *testing.T) {
+	var foo Foo
+	cache := slabgo.NewCacheSimple(foo)
+	cache.Alloc()
+	if leaks := cache.Leaks(); leaks != nil {
+		t.Errorf("Leaks() - expected nil, actual %v", leaks)
+	}
+}
@@ -0,0 +1,220 @@
+package slabgo
+
+import (
+	"context"
+	"io"
+	"unsafe"
+)
+
+// TypedConstructor is called when a new slab is created.
+// `objp` is a pointer of each new object.
+type TypedConstructor[T any] func(objp *T)
+
+// TypedDestructor is called when a slab is destroyed.
+// `objp` is a pointer of each object that is constructed or has been allocated.
+type TypedDestructor[T any] func(objp *T)
+
+// TypedDestructorE is a TypedDestructor that is told whether an object is still in use,
+// and returns an error which is reported by `TypedCache.Destroy`.
+type TypedDestructorE[T any] func(objp *T, inuse bool) error
+
+// Options for creating a TypedCache
+type TypedCacheOptions[T any] struct {
+	CacheOptions // Constructor, Destructor and DestructorE are overridden by typed ones
+	Constructor  TypedConstructor[T]
+	Destructor   TypedDestructor[T]
+	DestructorE  TypedDestructorE[T] // used instead of Destructor if set
+}
+
+// Type-safe storage for objects of type `T`
+type TypedCache[T any] struct {
+	cache *Cache
+}
+
+// Allocate an object from cache.
+// return a pointer of object, or nil if there is no available slab.
+func (c *TypedCache[T]) Alloc() *T {
+	if obj := c.cache.Alloc(); obj != nil {
+		return obj.(*T)
+	}
+	return nil
+}
+
+// Allocate an object from cache.
+// return ErrLimit, ErrGrowerDeclined or ErrDestroyed if there is no available object.
+func (c *TypedCache[T]) TryAlloc() (*T, error) {
+	obj, err := c.cache.TryAlloc()
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*T), nil
+}
+
+// Allocate an object from cache, and wait until an object is freed or `ctx` is done.
+func (c *TypedCache[T]) AllocWait(ctx context.Context) (*T, error) {
+	obj, err := c.cache.AllocWait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*T), nil
+}
+
+// Return an object to cache.
+// `objp` is a pointer of object.
+func (c *TypedCache[T]) Free(objp *T) bool {
+	return c.cache.FreePtr(unsafe.Pointer(objp))
+}
+
+// Allocate objects from cache as many as length of `dst`.
+// return number of objects allocated.
+func (c *TypedCache[T]) AllocN(dst []*T) int {
+	return c.cache.allocEach(len(dst), func(i int, obj interface{}) { dst[i] = obj.(*T) })
+}
+
+// Return objects to cache.
+// return number of objects freed.
+func (c *TypedCache[T]) FreeN(objs []*T) int {
+	return c.cache.freeEach(len(objs), func(i int) uintptr { return uintptr(unsafe.Pointer(objs[i])) })
+}
+
+// Allocate an object from cache, and return a handle of it.
+// return zero Handle and nil if there is no available object.
+func (c *TypedCache[T]) AllocHandle() (Handle, *T) {
+	if h, obj := c.cache.AllocHandle(); obj != nil {
+		return h, obj.(*T)
+	}
+	return 0, nil
+}
+
+// Return an object that `h` points, or nil if `h` is stale
+func (c *TypedCache[T]) Deref(h Handle) *T {
+	if obj := c.cache.Deref(h); obj != nil {
+		return obj.(*T)
+	}
+	return nil
+}
+
+// Return an object that `h` points to cache.
+// return ErrStaleHandle if `h` is stale.
+func (c *TypedCache[T]) FreeHandle(h Handle) error {
+	return c.cache.FreeHandle(h)
+}
+
+// Call `fn` for each object in use, until `fn` returns false.
+// See `Cache.Range` for objects freed or allocated during iteration.
+func (c *TypedCache[T]) Range(fn func(objp *T) bool) {
+	c.cache.Range(func(objp interface{}) bool { return fn(objp.(*T)) })
+}
+
+// Explicitly destroy a cache.
+// return an error same as `Cache.Destroy`.
+func (c *TypedCache[T]) Destroy() error {
+	return c.cache.Destroy()
+}
+
+// Explicitly destroy a cache if no object is in use.
+// return an error same as `Cache.DestroyStrict`.
+func (c *TypedCache[T]) DestroyStrict() error {
+	return c.cache.DestroyStrict()
+}
+
+// Allocate `n` contiguous objects from a slab.
+// return nil same as `Cache.AllocSlice`.
+func (c *TypedCache[T]) AllocSlice(n int) []T {
+	objs := c.cache.allocSlice(n)
+	if objs == nil {
+		return nil
+	}
+	return unsafe.Slice(objs[0].(*T), n)
+}
+
+// Return objects of a slice allocated by `TypedCache.AllocSlice` to cache.
+// `s` must have the same start and length.
+func (c *TypedCache[T]) FreeSlice(s []T) bool {
+	return c.cache.freeSlice(uintptr(unsafe.Pointer(unsafe.SliceData(s))), len(s)) == nil
+}
+
+// Write all objects in use to `w`.
+// return an error same as `Cache.Snapshot`.
+func (c *TypedCache[T]) Snapshot(w io.Writer) error {
+	return c.cache.Snapshot(w)
+}
+
+// Read objects from a snapshot, and allocate them.
+// return an error same as `Cache.Restore`.
+func (c *TypedCache[T]) Restore(r io.Reader) error {
+	return c.cache.Restore(r)
+}
+
+// Flush changes of objects to storage.
+// return an error same as `Cache.Sync`.
+func (c *TypedCache[T]) Sync() error {
+	return c.cache.Sync()
+}
+
+// Return length of object array within a slab
+func (c *TypedCache[T]) ObjectLen() int {
+	return c.cache.ObjectLen()
+}
+
+// Populates `s` with cache statistics
+func (c *TypedCache[T]) ReadStats(s *CacheStats) {
+	c.cache.ReadStats(s)
+}
+
+// Return the underlying Cache
+func (c *TypedCache[T]) Cache() *Cache {
+	return c.cache
+}
+
+// Create a TypedCache with options.
+// return nil if `T` is a zero-size type or `opts` is invalid.
+func NewTypedCache[T any](opts TypedCacheOptions[T]) *TypedCache[T] {
+	c, _ := NewTypedCacheE(opts)
+	return c
+}
+
+// Create a TypedCache with options.
+// return an error same as `NewCacheE`.
+func NewTypedCacheE[T any](opts TypedCacheOptions[T]) (*TypedCache[T], error) {
+	var obj T
+	cache, err := NewCacheE(obj, opts.cacheOptions())
+	if err != nil {
+		return nil, err
+	}
+	return &TypedCache[T]{cache: cache}, nil
+}
+
+// Open a persistent TypedCache whose slabs are stored in file `path`.
+// return an error same as `OpenPersistentCache`.
+func OpenPersistentTypedCache[T any](path string, opts TypedCacheOptions[T]) (*TypedCache[T], error) {
+	var obj T
+	cache, err := OpenPersistentCache(path, obj, opts.cacheOptions())
+	if err != nil {
+		return nil, err
+	}
+	return &TypedCache[T]{cache: cache}, nil
+}
+
+// return CacheOptions that typed callbacks are wrapped into
+func (opts TypedCacheOptions[T]) cacheOptions() CacheOptions {
+	copts := opts.CacheOptions
+	copts.Constructor = nil
+	copts.Destructor = nil
+	copts.DestructorE = nil
+	if ctor := opts.Constructor; ctor != nil {
+		copts.Constructor = func(objp interface{}) { ctor(objp.(*T)) }
+	}
+	if dtor := opts.Destructor; dtor != nil {
+		copts.Destructor = func(objp interface{}) { dtor(objp.(*T)) }
+	}
+	if dtor := opts.DestructorE; dtor != nil {
+		copts.DestructorE = func(objp interface{}, inuse bool) error { return dtor(objp.(*T), inuse) }
+	}
+	return copts
+}
+
+// Create a TypedCache simply.
+func NewTypedCacheSimple[T any]() *TypedCache[T] {
+	return NewTypedCache(TypedCacheOptions[T]{})
+}
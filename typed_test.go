@@ -0,0 +1,138 @@
+package slabgo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/k-sone/slabgo"
+)
+
+func typedCounter(n *int) func(*Foo) {
+	return func(objp *Foo) {
+		if objp != nil {
+			(*n)++
+		}
+	}
+}
+
+func TestTypedNew(t *testing.T) {
+	objLen := 32
+	cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{
+		CacheOptions: slabgo.CacheOptions{ObjLen: objLen},
+	})
+	if cache == nil {
+		t.Fatal("NewTypedCache() - failed")
+	}
+	if n := cache.ObjectLen(); n != objLen {
+		t.Errorf("ObjectLen() - expected [%d], actual [%d]", objLen, n)
+	}
+
+	if cache := slabgo.NewTypedCacheSimple[Foo](); cache == nil {
+		t.Error("NewTypedCacheSimple() - failed")
+	}
+	if cache := slabgo.NewTypedCacheSimple[struct{}](); cache != nil {
+		t.Error("NewTypedCacheSimple() - zero size failed")
+	}
+	if cache := slabgo.NewTypedCacheSimple[interface{}](); cache != nil {
+		t.Error("NewTypedCacheSimple() - interface failed")
+	}
+}
+
+func TestTypedAllocFree(t *testing.T) {
+	var foos []*Foo
+	var stats slabgo.CacheStats
+
+	objLen := 32
+	var gnum, rnum, cnum, dnum int
+
+	cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{
+		CacheOptions: slabgo.CacheOptions{
+			ObjLen: objLen,
+			Grower: func(s *slabgo.CacheStats) int { gnum++; return 1 },
+			Reaper: func(s *slabgo.CacheStats) int { rnum++; return 1 },
+		},
+		Constructor: typedCounter(&cnum),
+		Destructor:  typedCounter(&dnum),
+	})
+
+	for i := 0; i < objLen*2; i++ {
+		f := cache.Alloc()
+		if f == nil {
+			t.Fatalf("Alloc() - failed at %d", i)
+		}
+		foos = append(foos, f)
+	}
+
+	name := "typed Alloc()"
+	stats.TotalSlabs = 2
+	stats.InuseSlabs = 2
+	stats.TotalObjs = objLen * 2
+	stats.InuseObjs = objLen * 2
+	stats.Allocs = uint64(objLen * 2)
+	stats.Frees = 0
+	checkStats(t, name, cache.Cache(), &stats)
+	checkGrow(t, name, gnum, 2)
+	checkReap(t, name, rnum, 0)
+	checkConstruct(t, name, cnum, objLen*2)
+	checkDestruct(t, name, dnum, 0)
+
+	var foo Foo
+	if cache.Free(nil) {
+		t.Error("Free() - nil")
+	}
+	if cache.Free(&foo) {
+		t.Error("Free() - not allocated")
+	}
+
+	for i, f := range foos[objLen:] {
+		if !cache.Free(f) {
+			t.Errorf("Free() - failed at %d", i)
+		}
+	}
+
+	name = "typed Free()"
+	stats.TotalSlabs = 1
+	stats.InuseSlabs = 1
+	stats.TotalObjs = objLen
+	stats.InuseObjs = objLen
+	stats.Allocs = uint64(objLen * 2)
+	stats.Frees = uint64(objLen)
+	checkStats(t, name, cache.Cache(), &stats)
+	checkGrow(t, name, gnum, 2)
+	checkReap(t, name, rnum, 1)
+	checkConstruct(t, name, cnum, objLen*2)
+	checkDestruct(t, name, dnum, objLen)
+
+	cache.Destroy()
+	checkDestruct(t, "typed Destroy()", dnum, objLen*2)
+}
+
+func TestTypedAllocFailed(t *testing.T) {
+	cache := slabgo.NewTypedCache(slabgo.TypedCacheOptions[Foo]{
+		CacheOptions: slabgo.CacheOptions{
+			Grower: func(s *slabgo.CacheStats) int { return 0 },
+		},
+	})
+	if f := cache.Alloc(); f != nil {
+		t.Error("Alloc() - expected nil")
+	}
+}
+
+func TestTypedTryAlloc(t *testing.T) {
+	if _, err := slabgo.NewTypedCacheE[struct{}](slabgo.TypedCacheOptions[struct{}]{}); !errors.Is(err, slabgo.ErrZeroSize) {
+		t.Errorf("NewTypedCacheE() - expected [%v], actual [%v]", slabgo.ErrZeroSize, err)
+	}
+
+	cache, err := slabgo.NewTypedCacheE(slabgo.TypedCacheOptions[Foo]{
+		CacheOptions: slabgo.CacheOptions{MaxObjs: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewTypedCacheE() - failed: %v", err)
+	}
+	if f, err := cache.TryAlloc(); f == nil || err != nil {
+		t.Errorf("TryAlloc() - failed: %v", err)
+	}
+	if f, err := cache.TryAlloc(); f != nil || !errors.Is(err, slabgo.ErrLimit) {
+		t.Errorf("TryAlloc() - expected [%v], actual [%v]", slabgo.ErrLimit, err)
+	}
+}
@@ -0,0 +1,66 @@
+package slabgo
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrNotConcurrent is returned by `Cache.AllocWait` if a cache is not concurrent
+var ErrNotConcurrent = errors.New("slabgo: cache is not concurrent")
+
+// Allocate an object from cache.
+// If there is no available object, it blocks until an object is freed or `ctx` is done.
+// It requires `CacheOptions.Concurrent`, because objects must be freed by other goroutines.
+func (c *Cache) AllocWait(ctx context.Context) (interface{}, error) {
+	if !c.concurrent {
+		obj, err := c.TryAlloc()
+		if err != nil && err != ErrDestroyed {
+			err = ErrNotConcurrent
+		}
+		return obj, err
+	}
+
+	atomic.AddInt32(&c.waiters, 1)
+	defer atomic.AddInt32(&c.waiters, -1)
+
+	for {
+		// get a channel before trying, so as not to miss a free
+		wake := c.waitChan()
+		if obj := c.allocObj(); obj != nil {
+			if c.prepares {
+				c.prepare(obj)
+			}
+			return obj, nil
+		}
+		if err := c.allocErr(); err == ErrDestroyed {
+			return nil, err
+		}
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			atomic.AddUint64(&c.failed, 1)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Cache) waitChan() chan struct{} {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	if c.wake == nil {
+		c.wake = make(chan struct{})
+	}
+	return c.wake
+}
+
+// wake up all waiters
+func (c *Cache) wakeup() {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	if c.wake != nil {
+		close(c.wake)
+		c.wake = nil
+	}
+}
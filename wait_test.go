@@ -0,0 +1,179 @@
+package slabgo_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/k-sone/slabgo"
+)
+
+func TestWaitLimit(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	for _, opts := range []slabgo.CacheOptions{
+		{ObjLen: 8, MaxObjs: 10},
+		{ObjLen: 8, MaxBytes: uint64(unsafe.Sizeof(foo))*11 - 1},
+		{ObjLen: 8, MaxObjs: 20, MaxBytes: uint64(unsafe.Sizeof(foo)) * 10},
+		{ObjLen: 8, MaxObjs: 10, Concurrent: true, MagazineSize: -1},
+	} {
+		cache := slabgo.NewCache(foo, opts)
+		var foos []*Foo
+		for i := 0; i < 10; i++ {
+			f, ok := cache.Alloc().(*Foo)
+			if !ok {
+				t.Fatalf("Alloc() - failed at %d", i)
+			}
+			foos = append(foos, f)
+		}
+		if cache.Alloc() != nil {
+			t.Error("Alloc() - exceeded limit")
+		}
+		if n := cache.AllocN(make([]interface{}, 4)); n != 0 {
+			t.Errorf("AllocN() - exceeded limit %d", n)
+		}
+
+		name := "limit"
+		stats.TotalSlabs = 2
+		stats.InuseSlabs = 2
+		stats.TotalObjs = 16
+		stats.InuseObjs = 10
+		stats.Allocs = 10
+		stats.Frees = 0
+		checkStats(t, name, cache, &stats)
+
+		cache.Free(foos[0])
+		if n := cache.AllocN(make([]interface{}, 4)); n != 1 {
+			t.Errorf("AllocN() - expected [1], actual [%d]", n)
+		}
+	}
+}
+
+func TestWaitAllocWait(t *testing.T) {
+	var foo Foo
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, MaxObjs: 4, Concurrent: true})
+
+	var foos []*Foo
+	for i := 0; i < 4; i++ {
+		f, err := cache.AllocWait(context.Background())
+		if err != nil {
+			t.Fatalf("AllocWait() - failed: %v", err)
+		}
+		foos = append(foos, f.(*Foo))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := cache.AllocWait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AllocWait() - expected [%v], actual [%v]", context.DeadlineExceeded, err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cache.Free(foos[2])
+	}()
+	f, err := cache.AllocWait(context.Background())
+	if err != nil || f.(*Foo) != foos[2] {
+		t.Errorf("AllocWait() - expected [%p], actual [%v, %v]", foos[2], f, err)
+	}
+}
+
+func TestWaitNotConcurrent(t *testing.T) {
+	var foo Foo
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{MaxObjs: 1})
+
+	if _, err := cache.AllocWait(context.Background()); err != nil {
+		t.Errorf("AllocWait() - failed: %v", err)
+	}
+	if _, err := cache.AllocWait(context.Background()); !errors.Is(err, slabgo.ErrNotConcurrent) {
+		t.Errorf("AllocWait() - expected [%v], actual [%v]", slabgo.ErrNotConcurrent, err)
+	}
+}
+
+func TestWaitBoundedPool(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	limit := 8
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, MaxObjs: limit, Concurrent: true})
+
+	var inuse, peak int32
+	var wg sync.WaitGroup
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				obj, err := cache.AllocWait(context.Background())
+				if err != nil {
+					t.Errorf("AllocWait() - failed: %v", err)
+					return
+				}
+				n := atomic.AddInt32(&inuse, 1)
+				for p := atomic.LoadInt32(&peak); n > p && !atomic.CompareAndSwapInt32(&peak, p, n); p = atomic.LoadInt32(&peak) {
+				}
+				atomic.AddInt32(&inuse, -1)
+				cache.Free(obj)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > int32(limit) {
+		t.Errorf("AllocWait() - peak [%d] exceeded limit [%d]", peak, limit)
+	}
+	cache.ReadStats(&stats)
+	if stats.InuseObjs != 0 || stats.Allocs != 1600 || stats.Frees != 1600 {
+		t.Errorf("AllocWait() - unexpected stats %+v", stats)
+	}
+}
+
+func TestWaitLimitMagazines(t *testing.T) {
+	var foo Foo
+	var stats slabgo.CacheStats
+
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+	limit := 8
+	cache := slabgo.NewCache(foo, slabgo.CacheOptions{ObjLen: 8, MaxObjs: limit, Concurrent: true})
+
+	// load magazine 0, and then allocate from magazine 1 while magazine 0 is held
+	unpin := slabgo.PinMagazine(cache, 1)
+	ptrs := []unsafe.Pointer{unsafe.Pointer(cache.Alloc().(*Foo))}
+	unpin()
+	unpin = slabgo.PinMagazine(cache, 0)
+	obj, err := cache.TryAlloc()
+	unpin()
+	if err != nil {
+		t.Fatalf("TryAlloc() - failed: %v", err)
+	}
+	ptrs = append(ptrs, unsafe.Pointer(obj.(*Foo)))
+
+	for i := len(ptrs); i < limit; i++ {
+		obj, err := cache.TryAlloc()
+		if err != nil {
+			t.Fatalf("TryAlloc() - failed at %d: %v", i, err)
+		}
+		ptrs = append(ptrs, unsafe.Pointer(obj.(*Foo)))
+	}
+	if _, err := cache.TryAlloc(); !errors.Is(err, slabgo.ErrLimit) {
+		t.Errorf("TryAlloc() - expected [%v], actual [%v]", slabgo.ErrLimit, err)
+	}
+
+	// freed objects are held by both magazines
+	if n := cache.FreeN(ptrs); n != limit {
+		t.Fatalf("FreeN() - expected [%d], actual [%d]", limit, n)
+	}
+	if n := cache.AllocN(make([]interface{}, limit+1)); n != limit {
+		t.Errorf("AllocN() - expected [%d], actual [%d]", limit, n)
+	}
+	cache.ReadStats(&stats)
+	if stats.InuseObjs != limit {
+		t.Errorf("inuse objs: expected [%d], actual [%d]", limit, stats.InuseObjs)
+	}
+}